@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// record is an element of pipeline, the nested objects are records too
+type record = map[string]interface{}
+
+// readJSON reads the records of JSON Lines, or a JSON array of records.
+// the integers are int64 so the large ids keep their precision, the other numbers are float64
+func readJSON(r io.Reader) ([]record, error) {
+	br := bufio.NewReader(r)
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	ret := make([]record, 0)
+	if first, err := peekByte(br); err == io.EOF {
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	} else if first == '[' {
+		list := make([]record, 0)
+		if err := dec.Decode(&list); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		for _, rec := range list {
+			ret = append(ret, normalize(rec).(record))
+		}
+		return ret, nil
+	}
+	for line := 1; ; line++ {
+		rec := make(record)
+		if err := dec.Decode(&rec); err == io.EOF {
+			return ret, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid JSON record %d: %w", line, err)
+		}
+		ret = append(ret, normalize(rec).(record))
+	}
+}
+
+// peekByte returns the first byte which is not a space
+func peekByte(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// normalize converts the json.Number in v to int64 or float64
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case record:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	}
+	return v
+}
+
+// readCSV reads the records of CSV with header, and returns the columns of header.
+// the dotted columns like Position.City are nested records. the empty cells are null,
+// the integers, floats and booleans are converted, the other cells are strings
+func readCSV(r io.Reader) ([]record, []string, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return make([]record, 0), nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	ret := make([]record, 0)
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return ret, header, nil
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		rec := make(record)
+		for i, column := range header {
+			set(rec, strings.Split(column, "."), parseCell(row[i]))
+		}
+		ret = append(ret, rec)
+	}
+}
+
+// set sets the value of the nested record at path
+func set(rec record, path []string, v interface{}) {
+	for _, name := range path[:len(path)-1] {
+		next, ok := rec[name].(record)
+		if !ok {
+			next = make(record)
+			rec[name] = next
+		}
+		rec = next
+	}
+	rec[path[len(path)-1]] = v
+}
+
+func parseCell(cell string) interface{} {
+	if cell == "" {
+		return nil
+	}
+	if n, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(cell, 64); err == nil {
+		return f
+	}
+	if cell == "true" || cell == "false" {
+		return cell == "true"
+	}
+	return cell
+}
+
+// formatCell formats v as a cell of CSV or table, null is empty and the lists are JSON
+func formatCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64, int, bool:
+		return fmt.Sprint(v)
+	}
+	return encode(v)
+}
+
+// flatten puts the values of rec into row by the dotted columns
+func flatten(rec record, prefix string, row map[string]interface{}) {
+	for k, v := range rec {
+		if nested, ok := v.(record); ok && len(nested) > 0 {
+			flatten(nested, prefix+k+".", row)
+		} else {
+			row[prefix+k] = v
+		}
+	}
+}
+
+// table returns the header and the rows of records, the columns are ordered as known,
+// and the other columns follow in alphabetical order
+func table(records []record, known []string) ([]string, [][]string) {
+	flat := make([]map[string]interface{}, len(records))
+	seen := make(map[string]bool)
+	header := make([]string, 0)
+	for _, column := range known {
+		if !seen[column] {
+			seen[column] = true
+			header = append(header, column)
+		}
+	}
+	extra := make([]string, 0)
+	for i, rec := range records {
+		flat[i] = make(map[string]interface{})
+		flatten(rec, "", flat[i])
+		for column := range flat[i] {
+			if !seen[column] {
+				seen[column] = true
+				extra = append(extra, column)
+			}
+		}
+	}
+	sort.Strings(extra)
+	header = append(header, extra...)
+
+	rows := make([][]string, len(flat))
+	for i, f := range flat {
+		rows[i] = make([]string, len(header))
+		for j, column := range header {
+			rows[i][j] = formatCell(f[column])
+		}
+	}
+	return header, rows
+}
+
+// write writes records to w in format, which is jsonl, json, csv or table
+func write(w io.Writer, format string, records []record, columns []string) error {
+	switch format {
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, rec := range records {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		header, rows := table(records, columns)
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	case "table":
+		header, rows := table(records, columns)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q, it is jsonl, json, csv or table", format)
+}
+
+// encode returns the compact JSON of v, the keys of records are sorted so it is a canonical key
+func encode(v interface{}) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
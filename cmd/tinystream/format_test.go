@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadJSON(t *testing.T) {
+	got, err := readJSON(strings.NewReader(`{"Id": 1234567890123456789, "Age": 30.5}
+{"Position": {"City": "Paris"}, "Tags": [1]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []record{
+		{"Id": int64(1234567890123456789), "Age": 30.5},
+		{"Position": record{"City": "Paris"}, "Tags": []interface{}{int64(1)}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readJSON() = %v", got)
+	}
+	if got, err := readJSON(strings.NewReader(` [{"Id": 1}, {"Id": 2}]`)); err != nil || len(got) != 2 {
+		t.Errorf("readJSON() of array = %v, %v", got, err)
+	}
+	if got, err := readJSON(strings.NewReader("")); err != nil || len(got) != 0 {
+		t.Errorf("readJSON() of empty = %v, %v", got, err)
+	}
+	if _, err := readJSON(strings.NewReader("{\"Id\": 1}\n{\"Id\"")); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("readJSON() error = %v", err)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	got, header, err := readCSV(strings.NewReader("Id,Name,Position.City,Score,Active\n1,Ann,Paris,1.5,true\n2,,,,no\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []record{
+		{"Id": int64(1), "Name": "Ann", "Position": record{"City": "Paris"}, "Score": 1.5, "Active": true},
+		{"Id": int64(2), "Name": nil, "Position": record{"City": nil}, "Score": nil, "Active": "no"},
+	}
+	if !reflect.DeepEqual(got, want) || len(header) != 5 {
+		t.Errorf("readCSV() = %v, %v", got, header)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	records := []record{
+		{"Name": "Ann", "Id": int64(1), "Position": record{"City": "Paris"}},
+		{"Name": "Bob, Jr", "Id": int64(2), "Tags": []interface{}{"a"}},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"jsonl", `{"Id":1,"Name":"Ann","Position":{"City":"Paris"}}` + "\n" + `{"Id":2,"Name":"Bob, Jr","Tags":["a"]}` + "\n"},
+		{"csv", "Name,Id,Position.City,Tags\nAnn,1,Paris,\n\"Bob, Jr\",2,,\"[\"\"a\"\"]\"\n"},
+		{"table", "Name     Id  Position.City  Tags\nAnn      1   Paris          \nBob, Jr  2                  [\"a\"]\n"},
+	}
+	for _, tt := range tests {
+		buf := &bytes.Buffer{}
+		if err := write(buf, tt.format, records, []string{"Name", "Id"}); err != nil || buf.String() != tt.want {
+			t.Errorf("write(%s) = %q, %v, want %q", tt.format, buf.String(), err, tt.want)
+		}
+	}
+}
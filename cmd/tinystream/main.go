@@ -0,0 +1,137 @@
+// Command tinystream runs stream pipelines over JSON Lines and CSV data.
+//
+//	tinystream [-in file]... [-from jsonl|csv] pipeline [file...]
+//
+// the records are read from the files, or stdin if no file. the pipeline is the commands separated by |
+//
+//	filter condition          keeps the records matching condition, like .Age > 22 AND .Name IS NOT NULL
+//	sort field [desc], ...    sorts the records by fields, null is the greatest
+//	limit n                   keeps the first n records
+//	skip n                    skips the first n records
+//	unique [field, ...]       de-duplicates the records by fields, or by all fields
+//	map field [as name], ...  selects the fields into new records
+//	count                     prints the number of records
+//	join [separator]          prints the records joined by separator, the value is printed if a record has one field
+//	to jsonl|json|csv|table   prints the records in format, it is jsonl by default
+//
+// the fields are like .Position.City, the conditions are the WHERE clauses of stream.Query. for example
+//
+//	tinystream -in employees.jsonl 'filter .Age > 22 | sort .Id | limit 10 | to csv'
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"stream_test/stream"
+)
+
+// files is the flag of input files, which can be repeated
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *files) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with args, and returns the exit code
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tinystream", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var in files
+	flags.Var(&in, "in", "read records from `file`, - is stdin. it can be repeated")
+	from := flags.String("from", "", "the `format` of input, jsonl or csv. it is csv for the files ending with .csv, otherwise jsonl")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tinystream [-in file]... [-from jsonl|csv] pipeline [file...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	cmds, err := parsePipeline(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "tinystream:", err)
+		return 2
+	}
+	in = append(in, flags.Args()[1:]...)
+	if len(in) == 0 {
+		in = append(in, "-")
+	}
+
+	records, columns, err := readAll(in, *from, stdin)
+	if err == nil {
+		err = execute(stdout, stream.FromSlice(records), cmds, columns)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "tinystream:", err)
+		return 1
+	}
+	return 0
+}
+
+// readAll reads the records of files in order, and returns the columns of CSV headers
+func readAll(names []string, format string, stdin io.Reader) ([]record, []string, error) {
+	ret := make([]record, 0)
+	columns := make([]string, 0)
+	for _, name := range names {
+		records, header, err := readFile(name, format, stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		ret = append(ret, records...)
+		columns = append(columns, header...)
+	}
+	return ret, columns, nil
+}
+
+// readFile reads the records of the file name, or stdin if name is "-", and closes the file once it is read.
+// it returns the columns of the header if the file is CSV
+func readFile(name string, format string, stdin io.Reader) ([]record, []string, error) {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	kind := format
+	if kind == "" && strings.EqualFold(filepath.Ext(name), ".csv") {
+		kind = "csv"
+	}
+	var records []record
+	var header []string
+	var err error
+	switch kind {
+	case "", "jsonl", "json":
+		records, err = readJSON(r)
+	case "csv":
+		records, header, err = readCSV(r)
+	default:
+		return nil, nil, fmt.Errorf("unknown input format %q, it is jsonl or csv", format)
+	}
+	if err != nil {
+		if name == "-" {
+			name = "stdin"
+		}
+		return nil, nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return records, header, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	jsonl := filepath.Join(dir, "employees.jsonl")
+	csv := filepath.Join(dir, "more.csv")
+	os.WriteFile(jsonl, []byte(`{"Id":2,"Age":30}`+"\n"+`{"Id":1,"Age":20}`+"\n"), 0o644)
+	os.WriteFile(csv, []byte("Id,Age\n3,40\n"), 0o644)
+
+	tests := []struct {
+		args  []string
+		stdin string
+		code  int
+		out   string
+		err   string
+	}{
+		{args: []string{"-in", jsonl, "filter .Age > 22 | sort .Id | limit 10 | to csv"}, out: "Age,Id\n30,2\n"},
+		{args: []string{"sort .Id | map .Id | join", jsonl, csv}, out: "1,2,3\n"},
+		{args: []string{"-from", "csv", "count"}, stdin: "Id\n1\n2\n", out: "2\n"},
+		{args: []string{"count"}, stdin: "{\"Id\": 1}\n", out: "1\n"},
+		{args: []string{}, code: 2, err: "usage: tinystream"},
+		{args: []string{"grep x"}, code: 2, err: `tinystream: unknown command "grep"`},
+		{args: []string{"count", filepath.Join(dir, "missing.jsonl")}, code: 1, err: "missing.jsonl"},
+		{args: []string{"count"}, stdin: "{", code: 1, err: "tinystream: stdin: invalid JSON record 1"},
+	}
+	for _, tt := range tests {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(tt.args, strings.NewReader(tt.stdin), stdout, stderr)
+		if code != tt.code || stdout.String() != tt.out || !strings.Contains(stderr.String(), tt.err) {
+			t.Errorf("run(%q) = %v, %q, %q", tt.args, code, stdout.String(), stderr.String())
+		}
+	}
+}
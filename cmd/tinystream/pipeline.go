@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"stream_test/stream"
+)
+
+// command is a verb of pipeline with its argument
+type command struct {
+	verb string
+	arg  string
+}
+
+// verbs are the verbs of pipeline, the terminal ones must be the last
+var verbs = map[string]struct {
+	terminal bool
+	arg      int // arg is 1 if the argument is required, 0 if optional and -1 if none
+}{
+	"filter": {arg: 1},
+	"sort":   {arg: 1},
+	"limit":  {arg: 1},
+	"skip":   {arg: 1},
+	"unique": {arg: 0},
+	"map":    {arg: 1},
+	"count":  {terminal: true, arg: -1},
+	"join":   {terminal: true, arg: 0},
+	"to":     {terminal: true, arg: 1},
+}
+
+// formats are the output formats
+var formats = map[string]bool{"jsonl": true, "json": true, "csv": true, "table": true}
+
+// parsePipeline splits the pipeline into commands by |, which is not in quotes
+func parsePipeline(src string) ([]command, error) {
+	parts := make([]string, 0)
+	var quote rune
+	start := 0
+	for i, c := range src {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '|':
+			parts = append(parts, src[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, src[start:])
+
+	ret := make([]command, 0, len(parts))
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("empty command at %d", i+1)
+		}
+		verb, arg := part, ""
+		if sp := strings.IndexAny(part, " \t"); sp >= 0 {
+			verb, arg = part[:sp], strings.TrimSpace(part[sp+1:])
+		}
+		verb = strings.ToLower(verb)
+		spec, ok := verbs[verb]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("unknown command %q", verb)
+		case spec.terminal && i < len(parts)-1:
+			return nil, fmt.Errorf("%s must be the last command", verb)
+		case spec.arg > 0 && arg == "":
+			return nil, fmt.Errorf("%s requires an argument", verb)
+		case spec.arg < 0 && arg != "":
+			return nil, fmt.Errorf("%s takes no argument", verb)
+		case verb == "to" && !formats[strings.ToLower(arg)]:
+			return nil, fmt.Errorf("unknown output format %q, it is jsonl, json, csv or table", arg)
+		}
+		ret = append(ret, command{verb: verb, arg: arg})
+	}
+	return ret, nil
+}
+
+// fieldPrefix matches the dot before a field like .Age, which is not after a name or a number
+var fieldPrefix = regexp.MustCompile(`(^|[^\w.'])\.([A-Za-z_])`)
+
+// fields converts the fields like .Position.City in expr to the paths of query, the strings in quotes are kept
+func fields(expr string) string {
+	sb := strings.Builder{}
+	for i, part := range strings.Split(expr, "'") {
+		if i > 0 {
+			sb.WriteByte('\'')
+		}
+		// the odd parts are in quotes
+		if i%2 == 1 {
+			sb.WriteString(part)
+		} else {
+			sb.WriteString(fieldPrefix.ReplaceAllString(part, "$1$2"))
+		}
+	}
+	return sb.String()
+}
+
+// alias matches the alias of a column in map
+var alias = regexp.MustCompile(`(?i)\s+as\s+(\w+)$`)
+
+// columnsOf returns the names of columns selected by map, nil if any of them is not a field
+func columnsOf(arg string) []string {
+	ret := make([]string, 0)
+	for _, column := range strings.Split(fields(arg), ",") {
+		column = strings.TrimSpace(column)
+		if m := alias.FindStringSubmatch(column); m != nil {
+			ret = append(ret, m[1])
+		} else if strings.ContainsAny(column, "( ") {
+			return nil
+		} else {
+			ret = append(ret, column)
+		}
+	}
+	return ret
+}
+
+// compile appends the operations of commands to s except the terminal one.
+// columns is the order of columns, which is changed by map
+func compile(s stream.Stream[record], cmds []command, columns []string) (stream.Stream[record], []string, error) {
+	var err error
+	for _, cmd := range cmds {
+		// query is the query compiled from the command, it is in the error since the position is of it
+		query := ""
+		switch cmd.verb {
+		case "filter":
+			query = "SELECT * WHERE " + fields(cmd.arg)
+			s, err = stream.QueryElements(s, query)
+		case "sort":
+			query = "SELECT * ORDER BY " + fields(cmd.arg)
+			s, err = stream.QueryElements(s, query)
+		case "limit", "skip":
+			n, e := strconv.Atoi(cmd.arg)
+			if e != nil || n < 0 {
+				return nil, nil, fmt.Errorf("%s requires a non-negative integer, got %q", cmd.verb, cmd.arg)
+			}
+			if cmd.verb == "limit" {
+				s = s.Limit(n)
+			} else {
+				s = s.Skip(n)
+			}
+		case "unique":
+			s = s.Unique(uniqueKey(cmd.arg))
+		case "map":
+			query = "SELECT " + fields(cmd.arg)
+			if s, err = stream.Query(s, query); err == nil {
+				columns = columnsOf(cmd.arg)
+			}
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w of %q", cmd.verb, err, query)
+		}
+	}
+	return s, columns, nil
+}
+
+// uniqueKey returns the function of Unique, the records are the same if they have the same values of fields
+// separated by comma, or the same values of all fields if no field
+func uniqueKey(arg string) func(rec record) int {
+	keys := make([]stream.Function[stream.T, stream.R], 0)
+	if arg != "" {
+		for _, path := range strings.Split(fields(arg), ",") {
+			keys = append(keys, stream.Field(strings.TrimSpace(path)))
+		}
+	}
+	// the distinct keys are numbered, so they never collide
+	ids := make(map[string]int)
+	return func(rec record) int {
+		var k string
+		if len(keys) == 0 {
+			k = encode(rec)
+		} else {
+			values := make([]interface{}, len(keys))
+			for i, key := range keys {
+				values[i] = key(rec)
+			}
+			k = encode(values)
+		}
+		id, ok := ids[k]
+		if !ok {
+			id = len(ids)
+			ids[k] = id
+		}
+		return id
+	}
+}
+
+// execute executes the pipeline of commands over s, and writes the result to w
+func execute(w io.Writer, s stream.Stream[record], cmds []command, columns []string) (err error) {
+	terminal := command{verb: "to", arg: "jsonl"}
+	if len(cmds) > 0 && verbs[cmds[len(cmds)-1].verb].terminal {
+		terminal, cmds = cmds[len(cmds)-1], cmds[:len(cmds)-1]
+	}
+	s, columns, err = compile(s, cmds, columns)
+	if err != nil {
+		return err
+	}
+	// the operations without error panic if they fail
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	switch terminal.verb {
+	case "count":
+		_, err = fmt.Fprintln(w, s.Count())
+	case "join":
+		sep := unquote(terminal.arg)
+		if terminal.arg == "" {
+			sep = ","
+		}
+		_, err = fmt.Fprintln(w, stream.Map(s, func(rec record) string {
+			// the value of the only column is joined, like the names after map .Name
+			if len(rec) == 1 {
+				for _, v := range rec {
+					return formatCell(v)
+				}
+			}
+			return encode(rec)
+		}).Join(sep))
+	case "to":
+		records, e := s.ToSliceE()
+		if e != nil {
+			return e
+		}
+		err = write(w, strings.ToLower(terminal.arg), records, columns)
+	}
+	return
+}
+
+// unquote removes the quotes around s if any
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"stream_test/stream"
+)
+
+func TestParsePipeline(t *testing.T) {
+	got, err := parsePipeline("filter .Name = 'a|b' | SORT .Id desc|limit 10 | to csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []command{{"filter", ".Name = 'a|b'"}, {"sort", ".Id desc"}, {"limit", "10"}, {"to", "csv"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePipeline() = %v", got)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"filter .Age > 1 |", "empty command at 2"},
+		{"grep x", `unknown command "grep"`},
+		{"count | limit 1", "count must be the last command"},
+		{"sort", "sort requires an argument"},
+		{"count 1", "count takes no argument"},
+		{"to xml", `unknown output format "xml", it is jsonl, json, csv or table`},
+	}
+	for _, tt := range tests {
+		if _, err := parsePipeline(tt.src); err == nil || err.Error() != tt.want {
+			t.Errorf("parsePipeline(%q) error = %v, want %v", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	got := fields(".Age > 1.5 AND .Position.City = '.Paris' OR Name != 'x.y'")
+	if want := "Age > 1.5 AND Position.City = '.Paris' OR Name != 'x.y'"; got != want {
+		t.Errorf("fields() = %q, want %q", got, want)
+	}
+	if got := columnsOf(".Name, .Position.City AS city"); !reflect.DeepEqual(got, []string{"Name", "city"}) {
+		t.Errorf("columnsOf() = %v", got)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	records := []record{
+		{"Id": int64(3), "Name": "Cid", "Age": int64(40), "Position": record{"City": "Rome"}},
+		{"Id": int64(1), "Name": "Ann", "Age": int64(30), "Position": record{"City": "Paris"}},
+		{"Id": int64(2), "Name": "Bob", "Age": int64(20)},
+		{"Id": int64(1), "Name": "Ann", "Age": int64(30), "Position": record{"City": "Paris"}},
+	}
+	tests := []struct {
+		pipeline string
+		want     string
+	}{
+		{"filter .Age > 22 | sort .Id | limit 10 | map .Id, .Name | to csv", "Id,Name\n1,Ann\n1,Ann\n3,Cid\n"},
+		{"unique | count", "3\n"},
+		{"unique .Position.City | map .Name | join ' / '", "Cid / Ann / Bob\n"},
+		// null is the greatest, so it is the first in descending order
+		{"sort .Position.City desc, .Id | skip 1 | map .Id | join", "3,1,1\n"},
+		{"filter .Position.City IS NULL", `{"Age":20,"Id":2,"Name":"Bob"}` + "\n"},
+	}
+	for _, tt := range tests {
+		cmds, err := parsePipeline(tt.pipeline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		if err := execute(buf, stream.FromSlice(records), cmds, nil); err != nil || buf.String() != tt.want {
+			t.Errorf("execute(%q) = %q, %v, want %q", tt.pipeline, buf.String(), err, tt.want)
+		}
+	}
+
+	// the stages which fail are reported
+	cmds, _ := parsePipeline("sort .Name, .Position | to json")
+	err := execute(&bytes.Buffer{}, stream.FromSlice(records), cmds, nil)
+	if err == nil || !strings.Contains(err.Error(), "can't compare") {
+		t.Errorf("execute() error = %v", err)
+	}
+}
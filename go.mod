@@ -1,6 +1,6 @@
 module stream_test
 
-go 1.16
+go 1.18
 
 require (
 	github.com/Pallinder/go-randomdata v1.2.0
@@ -4,142 +4,13 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
-	"sync/atomic"
 	"time"
 	"unsafe"
 
+	"stream_test/rx"
 	"stream_test/stream"
 )
 
-func isNil(i interface{}) bool {
-	return i == nil
-}
-
-type Stream struct {
-	listeners []func(item interface{})
-	Done      chan struct{}
-	init      func(next func(item interface{}))
-}
-
-type ConcurrencyQueue struct {
-	rw   sync.RWMutex
-	data []interface{}
-}
-
-func (q *ConcurrencyQueue) Push(item interface{}) {
-	q.rw.Lock()
-	defer q.rw.Unlock()
-	q.data = append(q.data, item)
-}
-
-func (q *ConcurrencyQueue) Pop() interface{} {
-	q.rw.Lock()
-	defer q.rw.Unlock()
-	if len(q.data) == 0 {
-		return nil
-	}
-	item := q.data[0]
-	q.data = q.data[1:]
-	return item
-}
-
-const (
-	STOP      = 0
-	RUNNING   = 1
-	COMPLETED = 2
-)
-
-type TaskQueue struct {
-	queue      ConcurrencyQueue
-	status     int32
-	f          func(item interface{})
-	onComplete func()
-}
-
-func (t *TaskQueue) Push(item interface{}) {
-	t.queue.Push(item)
-}
-
-func (t *TaskQueue) Run() {
-	v := atomic.LoadInt32(&t.status)
-	if v == RUNNING {
-		return
-	}
-
-	if v == COMPLETED {
-		panic("????")
-	}
-	atomic.CompareAndSwapInt32(&t.status, STOP, RUNNING)
-	go func() {
-		for {
-			item := t.queue.Pop()
-			if isNil(item) {
-				atomic.CompareAndSwapInt32(&t.status, RUNNING, STOP)
-				break
-			}
-			t.f(item)
-		}
-
-		if atomic.LoadInt32(&t.status) == COMPLETED {
-			t.onComplete()
-		}
-	}()
-}
-
-func (t *TaskQueue) Complete() {
-	status := atomic.LoadInt32(&t.status)
-	if status == STOP {
-		t.onComplete()
-	} else {
-		atomic.StoreInt32(&t.status, COMPLETED)
-	}
-}
-
-func newStream() Stream {
-	return Stream{
-		Done: make(chan struct{}),
-	}
-}
-
-func (s *Stream) ConcatMap(f func(item interface{}) *Stream) *Stream {
-	newStream := newStream()
-	queue := TaskQueue{
-		f: func(item interface{}) {
-			subStream := f(item)
-			subStream.listeners = append(subStream.listeners, func(item interface{}) {
-				newStream.Next(item)
-			})
-			subStream.init(subStream.Next)
-			<-subStream.Done
-		},
-		onComplete: func() {
-			newStream.Complete()
-		},
-	}
-
-	s.listeners = append(s.listeners, func(item interface{}) {
-		queue.Push(item)
-		queue.Run()
-	})
-
-	go func() {
-		<-s.Done
-		queue.Complete()
-	}()
-
-	return &newStream
-}
-
-func (s *Stream) Complete() {
-	s.Done <- struct{}{}
-}
-
-func (s *Stream) Next(item interface{}) {
-	for _, f := range s.listeners {
-		f(item)
-	}
-}
-
 func modify(k []int) []int {
 	for i := 0; i < 100000; i++ {
 		k = append(k, 1, 2, 3, 4, 5)
@@ -165,37 +36,33 @@ func main() {
 }
 
 func main1() {
-	s := newStream()
-	ss := s.ConcatMap(func(item interface{}) *Stream {
-		inner := newStream()
-		inner.init = func(next func(item interface{})) {
+	s := rx.NewSubject[int]()
+	ss := rx.ConcatMap(s.Observable(), func(item int) rx.Observable[int] {
+		return rx.Create(func(e rx.Emitter[int]) {
 			wg := sync.WaitGroup{}
 			for i := 0; i < 3; i++ {
 				wg.Add(1)
 				go func(i int) {
 					defer wg.Done()
 					time.Sleep(time.Second * 2)
-					next(item.(int) + 1)
+					e.Next(item + 1)
 				}(i)
 			}
 			go func() {
 				wg.Wait()
-				inner.Complete()
+				e.Complete()
 			}()
-		}
-		return &inner
+		})
 	})
 
-	ss.listeners = append(ss.listeners, func(item interface{}) {
+	sub := ss.Subscribe(func(item int) {
 		fmt.Printf("%v\n", item)
-	})
+	}, nil, nil)
 
 	s.Next(1)
 	s.Next(10)
 	s.Next(100)
 	s.Complete()
 
-	defer func() {
-		<-ss.Done
-	}()
+	<-sub.Done()
 }
@@ -1,9 +1,12 @@
 package question_test
 
 import (
+	"math/rand"
+	"reflect"
 	"testing"
 
 	"stream_test"
+	"stream_test/stream"
 
 	"github.com/Pallinder/go-randomdata"
 	"github.com/sumory/idgen"
@@ -11,6 +14,7 @@ import (
 
 // ----------------------------------------
 var employees []*stream_test.Employee
+var shuffled []*stream_test.Employee
 var str string
 var strList []string
 var idGen *idgen.IdWorker
@@ -23,12 +27,74 @@ func BenchmarkQuestion1Sub1(b *testing.B) {
 	}
 }
 
+// the field path replaces the nil checks of Question1Sub1 by reflection
+func BenchmarkQuestion1Sub1Field(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		adults := stream.FromSlice(employees).FilterField("Age", stream.Gt(22))
+		stream.MapToInt(adults, func(e *stream_test.Employee) int64 {
+			return int64(*e.Age)
+		}).Sum()
+	}
+}
+
 func BenchmarkQuestion1Sub2(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		stream_test.Question1Sub2(employees)
 	}
 }
 
+func BenchmarkQuestion1Sub2Parallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stream.FromSlice(employees).
+			Parallel(0).
+			Sort(stream.ComparingInt64(func(e *stream_test.Employee) int64 {
+				return e.Id
+			})).
+			Limit(10).
+			ToSlice()
+	}
+}
+
+// the ids of employees are increasing, which are sorted quickly. shuffled ones show the cost of sorting
+func BenchmarkQuestion1Sub2Shuffled(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stream_test.Question1Sub2(shuffled)
+	}
+}
+
+func BenchmarkQuestion1Sub2ShuffledUnoptimized(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stream.FromSlice(shuffled).
+			Optimize(false).
+			Sort(stream.ComparingInt64(func(e *stream_test.Employee) int64 {
+				return e.Id
+			})).
+			Limit(10).
+			ToSlice()
+	}
+}
+
+func BenchmarkFilterAfterSort(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		filterAfterSort(stream.FromSlice(shuffled))
+	}
+}
+
+func BenchmarkFilterAfterSortUnoptimized(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		filterAfterSort(stream.FromSlice(shuffled).Optimize(false))
+	}
+}
+
+// filterAfterSort sorts all employees by id then keeps the elders, the optimizer filters them before sorting
+func filterAfterSort(s stream.Stream[*stream_test.Employee]) []*stream_test.Employee {
+	return s.Sort(stream.ComparingInt64(func(e *stream_test.Employee) int64 {
+		return e.Id
+	})).Filter(func(e *stream_test.Employee) bool {
+		return e.Age != nil && *e.Age > 90
+	}).ToSlice()
+}
+
 func TestQuestion1Sub1(t *testing.T) {
 	answer := stream_test.Question1Sub1(employees)
 	t.Log(answer)
@@ -49,6 +115,36 @@ func TestQuestion1Sub4(t *testing.T) {
 	t.Log(answer)
 }
 
+// the questions of Q1 answered by queries
+func TestQuestion1Query(t *testing.T) {
+	rows, err := stream.Query(stream.FromSlice(employees), "SELECT SUM(Age) AS total WHERE Age > 22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows.First().Get()["total"], stream_test.Question1Sub1(employees); got != want {
+		t.Errorf("Query() = %v, want %v", got, want)
+	}
+
+	top, err := stream.QueryElements(stream.FromSlice(shuffled), "SELECT * ORDER BY Id LIMIT 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := top.ToSlice(), stream_test.Question1Sub2(employees); !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryElements() = %v, want %v", got, want)
+	}
+
+	groups, err := stream.Query(stream.FromSlice(employees), "SELECT Age, COUNT(*) GROUP BY Age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := stream_test.Question1Sub4(employees)
+	groups.ForEach(func(row map[string]interface{}) {
+		if got := row["COUNT(*)"]; got != len(want[row["Age"].(int)]) {
+			t.Errorf("COUNT(*) of age %v = %v, want %v", row["Age"], got, len(want[row["Age"].(int)]))
+		}
+	})
+}
+
 func TestQuestion2Sub1(t *testing.T) {
 	answer := stream_test.Question2Sub1(str)
 	t.Log(answer)
@@ -71,6 +167,12 @@ func BenchmarkQuestion2Sub2(b *testing.B) {
 	}
 }
 
+func BenchmarkQuestion2Sub2Parallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stream.MaxBy(stream.FromSlice(strList).Parallel(0), stream_test.Question2Sub1)
+	}
+}
+
 func TestQuestion3Sub1(t *testing.T) {
 	answer := stream_test.Question3Sub1(str, 20)
 	t.Log(answer)
@@ -82,6 +184,10 @@ func init() {
 	for i := 0; i < 10000; i++ {
 		employees = append(employees, RandomInstance())
 	}
+	shuffled = append(shuffled, employees...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
 
 	str = randomdata.RandStringRunes(100)
 	for i := 0; i < 10000; i++ {
@@ -0,0 +1,86 @@
+package rx
+
+import (
+	"time"
+)
+
+// CatchError returns an Observable which pushes the items of o, if o fails it continues with
+// the Observable returned by handler, which may be o to re-subscribe it
+func (o Observable[T]) CatchError(handler func(err error) Observable[T]) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		o.subscribeWith(s.ctx, s.Next, func(err error) {
+			handler(err).subscribeWith(s.ctx, s.Next, s.Error, s.Complete)
+		}, s.Complete)
+	}}
+}
+
+// OnErrorReturn returns an Observable which pushes the items of o, if o fails it pushes the result of f
+// applied to the error then completes
+func (o Observable[T]) OnErrorReturn(f func(err error) T) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		o.subscribeWith(s.ctx, s.Next, func(err error) {
+			s.Next(f(err))
+			s.Complete()
+		}, s.Complete)
+	}}
+}
+
+// Retry returns an Observable which re-subscribes o if it fails, at most n times.
+// it fails with the last error if o still fails. o pushes its items again on each retry,
+// so the items pushed before the errors are pushed more than once
+func (o Observable[T]) Retry(n int) Observable[T] {
+	return o.retry(n, func(int) time.Duration {
+		return 0
+	})
+}
+
+// RetryWithBackoff is like Retry, but waits before re-subscribing o. it waits delay before the first retry,
+// and the delay doubles after each retry, up to max
+func (o Observable[T]) RetryWithBackoff(n int, delay time.Duration, max time.Duration) Observable[T] {
+	return o.retry(n, func(retry int) time.Duration {
+		d := delay
+		for i := 0; i < retry && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	})
+}
+
+// retry re-subscribes o if it fails at most n times, backoff returns the delay before the retry counted from 0
+func (o Observable[T]) retry(n int, backoff func(retry int) time.Duration) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		retries := 0
+		var subscribe func()
+		subscribe = func() {
+			if s.ctx.Err() != nil {
+				return
+			}
+			o.subscribeWith(s.ctx, s.Next, func(err error) {
+				if retries >= n {
+					s.Error(err)
+					return
+				}
+				d := backoff(retries)
+				retries++
+				if d <= 0 {
+					subscribe()
+					return
+				}
+				// the wait is stopped if s is unsubscribed
+				go func() {
+					timer := time.NewTimer(d)
+					defer timer.Stop()
+					select {
+					case <-timer.C:
+						subscribe()
+					case <-s.ctx.Done():
+					}
+				}()
+			}, s.Complete)
+		}
+		subscribe()
+	}}
+}
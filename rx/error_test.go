@@ -0,0 +1,171 @@
+package rx
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errFailed = errors.New("failed")
+
+// failing returns an Observable which pushes 1 and 2 then fails the first failures subscriptions,
+// and completes the later ones. subscriptions counts the subscriptions
+func failing(failures int32, subscriptions *int32) Observable[int] {
+	return Create(func(e Emitter[int]) {
+		n := atomic.AddInt32(subscriptions, 1)
+		e.Next(1)
+		e.Next(2)
+		if n <= failures {
+			e.Error(errFailed)
+		} else {
+			e.Complete()
+		}
+	})
+}
+
+func TestError(t *testing.T) {
+	got := make([]int, 0)
+	var errs []error
+	sub := Create(func(e Emitter[int]) {
+		e.Next(1)
+		e.Error(errFailed)
+		e.Next(2)
+		e.Error(errors.New("again"))
+		e.Complete()
+	}).Subscribe(func(item int) {
+		got = append(got, item)
+	}, func(err error) {
+		errs = append(errs, err)
+	}, func() {
+		t.Error("onComplete() after onError()")
+	})
+	<-sub.Done()
+	if !reflect.DeepEqual(got, []int{1}) || !reflect.DeepEqual(errs, []error{errFailed}) {
+		t.Errorf("Subscribe() = %v, %v", got, errs)
+	}
+
+	if got, err := Throw[int](errFailed).ToSlice(); err != errFailed || len(got) != 0 {
+		t.Errorf("Throw() = %v, %v", got, err)
+	}
+
+	// a Subject fails its subscribers, and the later ones at once
+	s := NewSubject[int]()
+	var first error
+	s.Observable().Subscribe(nil, func(err error) {
+		first = err
+	}, nil)
+	s.Error(errFailed)
+	s.Complete()
+	if _, err := s.Observable().ToSlice(); first != errFailed || err != errFailed {
+		t.Errorf("Subject errors = %v, %v", first, err)
+	}
+}
+
+func TestErrorPropagation(t *testing.T) {
+	// the inner Observable of 10 fails, so the ones after it are never subscribed
+	var subscribed int32
+	mapper := func(item int) Observable[int] {
+		atomic.AddInt32(&subscribed, 1)
+		if item == 10 {
+			return Throw[int](errFailed)
+		}
+		return plusOne(item)
+	}
+	got := make([]int, 0)
+	var err error
+	demo(t, func(o Observable[int]) Subscription {
+		return Reduce(ConcatMap(o, mapper), 0, func(acc int, item int) int {
+			got = append(got, item)
+			return acc + item
+		}).Subscribe(func(sum int) {
+			t.Errorf("Reduce() = %v after a failure", sum)
+		}, func(e error) {
+			err = e
+		}, nil)
+	})
+	if !reflect.DeepEqual(got, []int{2, 2, 2}) || err != errFailed || atomic.LoadInt32(&subscribed) != 2 {
+		t.Errorf("ConcatMap() = %v, %v, %v subscribed", got, err, subscribed)
+	}
+
+	// the other inner Observables of MergeMap are unsubscribed
+	got, err = MergeMap(FromValues(1, 10, 100), mapper).ToSlice()
+	if len(got) != 0 || err != errFailed {
+		t.Errorf("MergeMap() = %v, %v", got, err)
+	}
+}
+
+func TestCatchError(t *testing.T) {
+	var subscriptions int32
+	got, err := failing(1, &subscriptions).CatchError(func(err error) Observable[int] {
+		return FromValues(3, 4)
+	}).ToSlice()
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("CatchError() = %v, %v", got, err)
+	}
+
+	// the error of handler is not caught
+	subscriptions = 0
+	got, err = failing(1, &subscriptions).CatchError(func(err error) Observable[int] {
+		return Throw[int](errors.New("handler: " + err.Error()))
+	}).ToSlice()
+	if err == nil || err.Error() != "handler: failed" || !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Errorf("CatchError() = %v, %v", got, err)
+	}
+}
+
+func TestOnErrorReturn(t *testing.T) {
+	var subscriptions int32
+	got, err := failing(1, &subscriptions).OnErrorReturn(func(err error) int {
+		return -1
+	}).ToSlice()
+	if err != nil || !reflect.DeepEqual(got, []int{1, 2, -1}) {
+		t.Errorf("OnErrorReturn() = %v, %v", got, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		failures int32
+		n        int
+		want     []int
+		err      error
+	}{
+		{0, 2, []int{1, 2}, nil},
+		{2, 2, []int{1, 2, 1, 2, 1, 2}, nil},
+		{3, 2, []int{1, 2, 1, 2, 1, 2}, errFailed},
+		{1, 0, []int{1, 2}, errFailed},
+	}
+	for _, tt := range tests {
+		var subscriptions int32
+		got, err := failing(tt.failures, &subscriptions).Retry(tt.n).ToSlice()
+		if err != tt.err || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Retry(%v) of %v failures = %v, %v", tt.n, tt.failures, got, err)
+		}
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	var subscriptions int32
+	start := time.Now()
+	got, err := failing(3, &subscriptions).RetryWithBackoff(3, 10*time.Millisecond, 15*time.Millisecond).ToSlice()
+	// the delays are 10ms, 15ms and 15ms
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("RetryWithBackoff() takes %v", elapsed)
+	}
+	if err != nil || len(got) != 8 || subscriptions != 4 {
+		t.Errorf("RetryWithBackoff() = %v, %v", got, err)
+	}
+
+	// the source is not re-subscribed after Unsubscribe
+	subscriptions = 0
+	sub := failing(1, &subscriptions).RetryWithBackoff(1, time.Hour, time.Hour).Subscribe(nil, func(err error) {
+		t.Errorf("onError(%v) after Unsubscribe", err)
+	}, nil)
+	sub.Unsubscribe()
+	<-sub.Done()
+	if n := atomic.LoadInt32(&subscriptions); n != 1 {
+		t.Errorf("RetryWithBackoff() subscribed %v times after Unsubscribe", n)
+	}
+}
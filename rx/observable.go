@@ -0,0 +1,236 @@
+// Package rx is the package of push-based reactive streams. an Observable pushes items to its subscribers,
+// then a completion or an error which ends the subscription. the notifications to a subscriber are serialized,
+// even if the items are pushed by many goroutines
+package rx
+
+import (
+	"context"
+	"sync"
+)
+
+// Observable is a source of items, which starts pushing them to each subscriber when it is subscribed
+type Observable[T any] struct {
+	subscribe func(s *subscriber[T])
+}
+
+// Emitter pushes the items of an Observable created by Create to a subscriber, it is safe for concurrent use.
+// the notifications after Complete or Error, or after the subscriber unsubscribes, are ignored
+type Emitter[T any] interface {
+	// Next pushes an item
+	Next(item T)
+	// Complete ends the subscription
+	Complete()
+	// Error ends the subscription with err
+	Error(err error)
+	// Done is closed when the subscription ends, the producer should stop then
+	Done() <-chan struct{}
+}
+
+// Subscription is a subscription of an Observable
+type Subscription struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Unsubscribe stops receiving items, the Observable stops pushing as soon as possible.
+// no notification is received after it returns
+func (s Subscription) Unsubscribe() {
+	s.cancel()
+}
+
+// Done is closed when the subscription ends, that is the Observable completes, fails or it is unsubscribed
+func (s Subscription) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// subscriber receives the notifications of a subscription, which are serialized by mu
+type subscriber[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	terminated bool
+	onNext     func(T)
+	onError    func(error)
+	onComplete func()
+}
+
+func newSubscriber[T any](parent context.Context, onNext func(T), onError func(error), onComplete func()) *subscriber[T] {
+	ctx, cancel := context.WithCancel(parent)
+	return &subscriber[T]{ctx: ctx, cancel: cancel, onNext: onNext, onError: onError, onComplete: onComplete}
+}
+
+// Next calls onNext if the subscription is not ended
+func (s *subscriber[T]) Next(item T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.terminated || s.ctx.Err() != nil {
+		return
+	}
+	if s.onNext != nil {
+		s.onNext(item)
+	}
+}
+
+// Error ends the subscription with err, and calls onError
+func (s *subscriber[T]) Error(err error) {
+	s.terminate(func() {
+		if s.onError != nil {
+			s.onError(err)
+		}
+	})
+}
+
+// Complete ends the subscription, and calls onComplete
+func (s *subscriber[T]) Complete() {
+	s.terminate(s.onComplete)
+}
+
+// terminate calls the terminal notification f only once, then the subscription ends
+func (s *subscriber[T]) terminate(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.terminated || s.ctx.Err() != nil {
+		return
+	}
+	s.terminated = true
+	if f != nil {
+		f()
+	}
+	s.cancel()
+}
+
+func (s *subscriber[T]) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// subscribeWith subscribes o in ctx, the subscription ends when ctx is done
+func (o Observable[T]) subscribeWith(ctx context.Context, onNext func(T), onError func(error), onComplete func()) *subscriber[T] {
+	s := newSubscriber(ctx, onNext, onError, onComplete)
+	o.subscribe(s)
+	return s
+}
+
+// Subscribe subscribes o with the callbacks, any of them may be nil. the callbacks are never called at the same time,
+// onNext is called for each item, then onComplete if o completes, or onError if it fails.
+// it returns when o has pushed the items which are pushed synchronously, so it may have ended already
+func (o Observable[T]) Subscribe(onNext func(item T), onError func(err error), onComplete func()) Subscription {
+	s := o.subscribeWith(context.Background(), onNext, onError, onComplete)
+	return Subscription{ctx: s.ctx, cancel: s.cancel}
+}
+
+// Create creates an Observable, f pushes the items to each subscriber by the Emitter.
+// f is called in the goroutine which subscribes, it may push the items in other goroutines
+func Create[T any](f func(e Emitter[T])) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		f(s)
+	}}
+}
+
+// Throw creates an Observable which fails with err at once
+func Throw[T any](err error) Observable[T] {
+	return Create(func(e Emitter[T]) {
+		e.Error(err)
+	})
+}
+
+// FromSlice creates an Observable which pushes the elements of slice then completes
+func FromSlice[T any](slice []T) Observable[T] {
+	return Create(func(e Emitter[T]) {
+		for _, item := range slice {
+			select {
+			case <-e.Done():
+				return
+			default:
+				e.Next(item)
+			}
+		}
+		e.Complete()
+	})
+}
+
+// FromValues creates an Observable which pushes items then completes
+func FromValues[T any](items ...T) Observable[T] {
+	return FromSlice(items)
+}
+
+// Subject is an Observable which pushes the items to all subscribers when Next is called,
+// the subscribers receive the items pushed after they subscribe. it is safe for concurrent use
+type Subject[T any] struct {
+	mu          sync.Mutex
+	subscribers []*subscriber[T]
+	completed   bool
+	err         error
+}
+
+// NewSubject creates a Subject
+func NewSubject[T any]() *Subject[T] {
+	return &Subject[T]{}
+}
+
+// Observable returns the Observable of subject, the subscribers subscribed after Complete or Error
+// complete or fail at once
+func (s *Subject[T]) Observable() Observable[T] {
+	return Observable[T]{subscribe: func(sub *subscriber[T]) {
+		s.mu.Lock()
+		if s.completed {
+			err := s.err
+			s.mu.Unlock()
+			if err != nil {
+				sub.Error(err)
+			} else {
+				sub.Complete()
+			}
+			return
+		}
+		s.subscribers = append(s.subscribers, sub)
+		s.mu.Unlock()
+	}}
+}
+
+// active returns the subscribers which are not ended, the ended ones are removed
+func (s *Subject[T]) active() []*subscriber[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make([]*subscriber[T], 0, len(s.subscribers))
+	for _, sub := range s.subscribers {
+		if sub.ctx.Err() == nil {
+			ret = append(ret, sub)
+		}
+	}
+	s.subscribers = ret
+	return ret
+}
+
+// Next pushes item to the subscribers
+func (s *Subject[T]) Next(item T) {
+	for _, sub := range s.active() {
+		sub.Next(item)
+	}
+}
+
+// Complete completes the subscribers, the notifications after it are ignored
+func (s *Subject[T]) Complete() {
+	for _, sub := range s.end(nil) {
+		sub.Complete()
+	}
+}
+
+// Error fails the subscribers with err, the notifications after it are ignored
+func (s *Subject[T]) Error(err error) {
+	for _, sub := range s.end(err) {
+		sub.Error(err)
+	}
+}
+
+// end ends subject with err, and returns the subscribers to notify. it returns nil if subject is ended already
+func (s *Subject[T]) end(err error) []*subscriber[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.completed {
+		return nil
+	}
+	subs := s.subscribers
+	s.completed, s.err, s.subscribers = true, err, nil
+	return subs
+}
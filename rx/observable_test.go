@@ -0,0 +1,115 @@
+package rx
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	got := make([]int, 0)
+	completed := 0
+	sub := FromValues(1, 2, 3).Subscribe(func(item int) {
+		got = append(got, item)
+	}, func(err error) {
+		t.Errorf("onError(%v)", err)
+	}, func() {
+		completed++
+	})
+	<-sub.Done()
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) || completed != 1 {
+		t.Errorf("Subscribe() = %v, completed %v times", got, completed)
+	}
+
+	// each subscriber receives all items
+	if got, err := FromSlice([]string{"a", "b"}).ToSlice(); err != nil || !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("ToSlice() = %v, %v", got, err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	// the items pushed by many goroutines are serialized, and the ones after Complete are ignored
+	o := Create(func(e Emitter[int]) {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				e.Next(i)
+			}(i)
+		}
+		wg.Wait()
+		e.Complete()
+		e.Next(100)
+		e.Complete()
+	})
+	sum, completed := 0, 0
+	sub := o.Subscribe(func(item int) {
+		sum += item
+	}, nil, func() {
+		completed++
+	})
+	<-sub.Done()
+	if sum != 4950 || completed != 1 {
+		t.Errorf("Subscribe() = %v, completed %v times", sum, completed)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	next := make(chan int)
+	stopped := make(chan struct{})
+	o := Create(func(e Emitter[int]) {
+		go func() {
+			defer close(stopped)
+			for i := 0; ; i++ {
+				select {
+				case <-e.Done():
+					return
+				case next <- i:
+					e.Next(i)
+				}
+			}
+		}()
+	})
+	got := make([]int, 0)
+	sub := o.Subscribe(func(item int) {
+		got = append(got, item)
+	}, nil, func() {
+		t.Error("onComplete() after Unsubscribe")
+	})
+	<-next
+	<-next
+	sub.Unsubscribe()
+	<-sub.Done()
+	<-stopped
+	if len(got) > 2 {
+		t.Errorf("Subscribe() = %v after Unsubscribe", got)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	s := NewSubject[int]()
+	s.Next(0)
+	first, second := make([]int, 0), make([]int, 0)
+	sub1 := s.Observable().Subscribe(func(item int) {
+		first = append(first, item)
+	}, nil, nil)
+	s.Next(1)
+	sub2 := s.Observable().Subscribe(func(item int) {
+		second = append(second, item)
+	}, nil, nil)
+	s.Next(2)
+	sub2.Unsubscribe()
+	s.Next(3)
+	s.Complete()
+	s.Next(4)
+	<-sub1.Done()
+	if !reflect.DeepEqual(first, []int{1, 2, 3}) || !reflect.DeepEqual(second, []int{2}) {
+		t.Errorf("Subject = %v, %v", first, second)
+	}
+
+	// the subscribers after Complete complete at once
+	if got, err := s.Observable().ToSlice(); err != nil || len(got) != 0 {
+		t.Errorf("ToSlice() after Complete = %v, %v", got, err)
+	}
+}
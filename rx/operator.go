@@ -0,0 +1,169 @@
+package rx
+
+import (
+	"sync"
+)
+
+// Map returns an Observable which pushes the results of mapper applied to the items of o
+func Map[T, R any](o Observable[T], mapper func(T) R) Observable[R] {
+	return Observable[R]{subscribe: func(s *subscriber[R]) {
+		o.subscribeWith(s.ctx, func(item T) {
+			s.Next(mapper(item))
+		}, s.Error, s.Complete)
+	}}
+}
+
+// Filter returns an Observable which pushes the items of o that match predicate
+func (o Observable[T]) Filter(predicate func(T) bool) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		o.subscribeWith(s.ctx, func(item T) {
+			if predicate(item) {
+				s.Next(item)
+			}
+		}, s.Error, s.Complete)
+	}}
+}
+
+// Take returns an Observable which pushes the first n items of o then completes, o is unsubscribed then
+func (o Observable[T]) Take(n int) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		if n <= 0 {
+			s.Complete()
+			return
+		}
+		count := 0
+		// o is subscribed in the context of s, so it is unsubscribed when s completes
+		o.subscribeWith(s.ctx, func(item T) {
+			count++
+			s.Next(item)
+			if count == n {
+				s.Complete()
+			}
+		}, s.Error, s.Complete)
+	}}
+}
+
+// Skip returns an Observable which pushes the items of o after the first n ones
+func (o Observable[T]) Skip(n int) Observable[T] {
+	return Observable[T]{subscribe: func(s *subscriber[T]) {
+		count := 0
+		o.subscribeWith(s.ctx, func(item T) {
+			if count < n {
+				count++
+				return
+			}
+			s.Next(item)
+		}, s.Error, s.Complete)
+	}}
+}
+
+// ConcatMap returns an Observable which pushes the items of the Observables mapped from the items of o,
+// one Observable after another in the order of the items. the next one is subscribed when the previous one completes,
+// it fails as soon as o or any of them fails, the others are unsubscribed then
+func ConcatMap[T, R any](o Observable[T], mapper func(T) Observable[R]) Observable[R] {
+	return Observable[R]{subscribe: func(s *subscriber[R]) {
+		var mu sync.Mutex
+		queue := make([]T, 0)
+		// active is true if an inner Observable is subscribed, draining is true if a goroutine is in drain
+		active, draining, completed := false, false, false
+
+		// drain subscribes the queued items in a loop, so the inner Observables which complete synchronously
+		// don't recurse
+		var drain func()
+		drain = func() {
+			mu.Lock()
+			if draining {
+				mu.Unlock()
+				return
+			}
+			draining = true
+			// the queue is dropped if s is ended by a failure
+			for !active && len(queue) > 0 && s.ctx.Err() == nil {
+				item := queue[0]
+				queue = queue[1:]
+				active = true
+				mu.Unlock()
+				mapper(item).subscribeWith(s.ctx, s.Next, s.Error, func() {
+					mu.Lock()
+					active = false
+					mu.Unlock()
+					drain()
+				})
+				mu.Lock()
+			}
+			draining = false
+			done := completed && !active && len(queue) == 0
+			mu.Unlock()
+			if done {
+				s.Complete()
+			}
+		}
+
+		o.subscribeWith(s.ctx, func(item T) {
+			mu.Lock()
+			queue = append(queue, item)
+			mu.Unlock()
+			drain()
+		}, s.Error, func() {
+			mu.Lock()
+			completed = true
+			mu.Unlock()
+			drain()
+		})
+	}}
+}
+
+// MergeMap returns an Observable which pushes the items of the Observables mapped from the items of o,
+// all of them are subscribed at once so their items are interleaved. it completes when all of them complete,
+// it fails as soon as o or any of them fails, the others are unsubscribed then
+func MergeMap[T, R any](o Observable[T], mapper func(T) Observable[R]) Observable[R] {
+	return Observable[R]{subscribe: func(s *subscriber[R]) {
+		var mu sync.Mutex
+		// active is the number of Observables which are not completed, o included
+		active := 1
+		complete := func() {
+			mu.Lock()
+			active--
+			done := active == 0
+			mu.Unlock()
+			if done {
+				s.Complete()
+			}
+		}
+
+		o.subscribeWith(s.ctx, func(item T) {
+			mu.Lock()
+			active++
+			mu.Unlock()
+			mapper(item).subscribeWith(s.ctx, s.Next, s.Error, complete)
+		}, s.Error, complete)
+	}}
+}
+
+// Reduce returns an Observable which pushes the result of accumulator applied to identity and the items of o,
+// when o completes
+func Reduce[T, R any](o Observable[T], identity R, accumulator func(R, T) R) Observable[R] {
+	return Observable[R]{subscribe: func(s *subscriber[R]) {
+		result := identity
+		o.subscribeWith(s.ctx, func(item T) {
+			result = accumulator(result, item)
+		}, s.Error, func() {
+			s.Next(result)
+			s.Complete()
+		})
+	}}
+}
+
+// ToSlice subscribes o and waits until it ends, then returns the items pushed, with the error if o fails.
+// it never returns if o never completes or fails
+func (o Observable[T]) ToSlice() ([]T, error) {
+	ret := make([]T, 0)
+	var err error
+	sub := o.Subscribe(func(item T) {
+		ret = append(ret, item)
+	}, func(e error) {
+		err = e
+	}, nil)
+	<-sub.Done()
+	return ret, err
+}
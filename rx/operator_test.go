@@ -0,0 +1,177 @@
+package rx
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// demo is the source of main1 in index: 1, 10 and 100 are pushed by a Subject, subscribed by subscribe
+func demo(t *testing.T, subscribe func(o Observable[int]) Subscription) {
+	t.Helper()
+	s := NewSubject[int]()
+	sub := subscribe(s.Observable())
+	s.Next(1)
+	s.Next(10)
+	s.Next(100)
+	s.Complete()
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscription never ends")
+	}
+}
+
+// plusOne is the inner Observable of main1, which pushes item+1 by 3 goroutines at the same time then completes
+func plusOne(item int) Observable[int] {
+	return Create(func(e Emitter[int]) {
+		wg := sync.WaitGroup{}
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(10 * time.Millisecond)
+				e.Next(item + 1)
+			}()
+		}
+		go func() {
+			wg.Wait()
+			e.Complete()
+		}()
+	})
+}
+
+// collect subscribes o, and appends the items to got
+func collect[T any](o Observable[T], got *[]T) Subscription {
+	return o.Subscribe(func(item T) {
+		*got = append(*got, item)
+	}, nil, nil)
+}
+
+func TestConcatMap(t *testing.T) {
+	got := make([]int, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(ConcatMap(o, plusOne), &got)
+	})
+	if want := []int{2, 2, 2, 11, 11, 11, 101, 101, 101}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ConcatMap() = %v, want %v", got, want)
+	}
+
+	// the inner Observables which complete synchronously are concatenated too
+	got, err := ConcatMap(FromValues(1, 2, 3), func(item int) Observable[int] {
+		return FromValues(item, item*10)
+	}).ToSlice()
+	if err != nil || !reflect.DeepEqual(got, []int{1, 10, 2, 20, 3, 30}) {
+		t.Errorf("ConcatMap() = %v, %v", got, err)
+	}
+}
+
+func TestMergeMap(t *testing.T) {
+	got := make([]int, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(MergeMap(o, plusOne), &got)
+	})
+	// the inner Observables are subscribed at once, so the items are interleaved
+	sort.Ints(got)
+	if want := []int{2, 2, 2, 11, 11, 11, 101, 101, 101}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeMap() = %v, want %v", got, want)
+	}
+}
+
+func TestMap(t *testing.T) {
+	got := make([]string, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(Map(ConcatMap(o, plusOne), func(item int) string {
+			return strconv.Itoa(item)
+		}), &got)
+	})
+	if want := []string{"2", "2", "2", "11", "11", "11", "101", "101", "101"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Map() = %v, want %v", got, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	got := make([]int, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(ConcatMap(o, plusOne).Filter(func(item int) bool {
+			return item > 10
+		}), &got)
+	})
+	if want := []int{11, 11, 11, 101, 101, 101}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %v, want %v", got, want)
+	}
+}
+
+func TestTake(t *testing.T) {
+	got := make([]int, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(ConcatMap(o, plusOne).Take(4), &got)
+	})
+	if want := []int{2, 2, 2, 11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Take() = %v, want %v", got, want)
+	}
+
+	// the source is unsubscribed after n items, so Take ends an infinite Observable
+	stopped := make(chan struct{})
+	naturals := Create(func(e Emitter[int]) {
+		go func() {
+			defer close(stopped)
+			for i := 0; ; i++ {
+				select {
+				case <-e.Done():
+					return
+				default:
+					e.Next(i)
+				}
+			}
+		}()
+	})
+	if got, err := naturals.Take(3).ToSlice(); err != nil || !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("Take() = %v, %v", got, err)
+	}
+	<-stopped
+	if got, err := naturals.Take(0).ToSlice(); err != nil || len(got) != 0 {
+		t.Errorf("Take(0) = %v, %v", got, err)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	got := make([]int, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(ConcatMap(o, plusOne).Skip(4), &got)
+	})
+	if want := []int{11, 11, 101, 101, 101}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Skip() = %v, want %v", got, want)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	got := make([]int, 0)
+	demo(t, func(o Observable[int]) Subscription {
+		return collect(Reduce(MergeMap(o, plusOne), 0, func(acc int, item int) int {
+			return acc + item
+		}), &got)
+	})
+	if want := []int{342}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Reduce() = %v, want %v", got, want)
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	// ToSlice blocks until the source completes, so the items are pushed by another goroutine
+	o := ConcatMap(Create(func(e Emitter[int]) {
+		go func() {
+			e.Next(1)
+			e.Next(10)
+			e.Next(100)
+			e.Complete()
+		}()
+	}), plusOne)
+	got, err := o.ToSlice()
+	if want := []int{2, 2, 2, 11, 11, 11, 101, 101, 101}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ToSlice() = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,261 @@
+package stream
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Collector describes how to collect elements of type T into a result of type R,
+// A is the type of the mutable container used while accumulating
+type Collector[T, A, R any] struct {
+	Supplier    Supplier[A]        // Supplier creates a new container
+	Accumulator func(acc A, e T) A // Accumulator folds an element into the container
+	// Combiner merges the container of right part into the left one, it is required to collect parallel.
+	// the stream is collected sequentially if it is nil
+	Combiner BinaryOperator[A]
+	Finisher Function[A, R] // Finisher converts the container to the result, the container is the result if nil
+}
+
+// finish converts the container to the result
+func (c Collector[T, A, R]) finish(acc A) R {
+	if c.Finisher == nil {
+		return as[R](acc)
+	}
+	return c.Finisher(acc)
+}
+
+// Collect collects elements of s with collector.
+// when the stream executes parallel, each chunk is collected into its own container,
+// and the containers are merged by Combiner in encounter order
+func Collect[T, A, R any](s Stream[T], c Collector[T, A, R]) R {
+	var acc A
+	sg := stage{
+		name: "Collect",
+		action: func(ele interface{}, i int) (interface{}, int) {
+			acc = c.Supplier()
+			for _, e := range ele.([]interface{}) {
+				acc = c.Accumulator(acc, as[T](e))
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	}
+	if c.Combiner != nil {
+		sg.parallel = func(chunks [][]interface{}, workers int, stopped func() bool) []interface{} {
+			parts := make([]A, len(chunks))
+			runWorkers(workers, len(chunks), func(i int) {
+				part := c.Supplier()
+				for _, e := range chunks[i] {
+					if stopped() {
+						return
+					}
+					part = c.Accumulator(part, as[T](e))
+				}
+				parts[i] = part
+			})
+			acc = c.Supplier()
+			for _, part := range parts {
+				acc = c.Combiner(acc, part)
+			}
+			return nil
+		}
+	}
+	s.(*stream[T]).terminate(sg)
+	return c.finish(acc)
+}
+
+// ToSlice returns a Collector which collects elements into a slice
+func ToSlice[T any]() Collector[T, []T, []T] {
+	return Collector[T, []T, []T]{
+		Supplier: func() []T {
+			return make([]T, 0)
+		},
+		Accumulator: func(acc []T, e T) []T {
+			return append(acc, e)
+		},
+		Combiner: func(left []T, right []T) []T {
+			return append(left, right...)
+		},
+	}
+}
+
+// ToSet returns a Collector which collects elements into a set
+func ToSet[T comparable]() Collector[T, map[T]bool, map[T]bool] {
+	return Collector[T, map[T]bool, map[T]bool]{
+		Supplier: func() map[T]bool {
+			return make(map[T]bool)
+		},
+		Accumulator: func(acc map[T]bool, e T) map[T]bool {
+			acc[e] = true
+			return acc
+		},
+		Combiner: func(left map[T]bool, right map[T]bool) map[T]bool {
+			for e := range right {
+				left[e] = true
+			}
+			return left
+		},
+	}
+}
+
+// ToMap returns a Collector which collects elements into a map.
+// the values of same key are merged by merge, it panics on duplicate key if merge is nil
+func ToMap[T any, K comparable, V any](key Function[T, K], value Function[T, V], merge BinaryOperator[V]) Collector[T, map[K]V, map[K]V] {
+	put := func(m map[K]V, k K, v V) {
+		if old, ok := m[k]; ok {
+			if merge == nil {
+				panic(fmt.Sprintf("duplicate key %v", k))
+			}
+			v = merge(old, v)
+		}
+		m[k] = v
+	}
+	return Collector[T, map[K]V, map[K]V]{
+		Supplier: func() map[K]V {
+			return make(map[K]V)
+		},
+		Accumulator: func(acc map[K]V, e T) map[K]V {
+			put(acc, key(e), value(e))
+			return acc
+		},
+		Combiner: func(left map[K]V, right map[K]V) map[K]V {
+			for k, v := range right {
+				put(left, k, v)
+			}
+			return left
+		},
+	}
+}
+
+// GroupingBy returns a Collector which groups elements by classifier,
+// and collects the elements of each group with downstream
+func GroupingBy[T any, K comparable, A, D any](classifier Function[T, K], downstream Collector[T, A, D]) Collector[T, map[K]A, map[K]D] {
+	ret := Collector[T, map[K]A, map[K]D]{
+		Supplier: func() map[K]A {
+			return make(map[K]A)
+		},
+		Accumulator: func(acc map[K]A, e T) map[K]A {
+			k := classifier(e)
+			container, ok := acc[k]
+			if !ok {
+				container = downstream.Supplier()
+			}
+			acc[k] = downstream.Accumulator(container, e)
+			return acc
+		},
+		Finisher: func(acc map[K]A) map[K]D {
+			ret := make(map[K]D, len(acc))
+			for k, container := range acc {
+				ret[k] = downstream.finish(container)
+			}
+			return ret
+		},
+	}
+	if downstream.Combiner != nil {
+		ret.Combiner = func(left map[K]A, right map[K]A) map[K]A {
+			for k, container := range right {
+				if l, ok := left[k]; ok {
+					container = downstream.Combiner(l, container)
+				}
+				left[k] = container
+			}
+			return left
+		}
+	}
+	return ret
+}
+
+// PartitioningBy returns a Collector which partitions elements by predicate,
+// and collects the elements of each partition with downstream. the result always has both true and false keys
+func PartitioningBy[T, A, D any](predicate Predicate[T], downstream Collector[T, A, D]) Collector[T, map[bool]A, map[bool]D] {
+	group := GroupingBy(Function[T, bool](predicate), downstream)
+	group.Supplier = func() map[bool]A {
+		return map[bool]A{
+			true:  downstream.Supplier(),
+			false: downstream.Supplier(),
+		}
+	}
+	return group
+}
+
+// Mapping returns a Collector which maps elements by mapper before they are collected by downstream
+func Mapping[T, U, A, R any](mapper Function[T, U], downstream Collector[U, A, R]) Collector[T, A, R] {
+	return Collector[T, A, R]{
+		Supplier: downstream.Supplier,
+		Accumulator: func(acc A, e T) A {
+			return downstream.Accumulator(acc, mapper(e))
+		},
+		Combiner: downstream.Combiner,
+		Finisher: downstream.finish,
+	}
+}
+
+// Counting returns a Collector which counts elements
+func Counting[T any]() Collector[T, int, int] {
+	return Collector[T, int, int]{
+		Supplier: func() int {
+			return 0
+		},
+		Accumulator: func(acc int, e T) int {
+			return acc + 1
+		},
+		Combiner: func(left int, right int) int {
+			return left + right
+		},
+	}
+}
+
+// Summing returns a Collector which sums the numbers produced by f
+func Summing[T any, N Number](f Function[T, N]) Collector[T, N, N] {
+	return Collector[T, N, N]{
+		Supplier: func() N {
+			return 0
+		},
+		Accumulator: func(acc N, e T) N {
+			return acc + f(e)
+		},
+		Combiner: func(left N, right N) N {
+			return left + right
+		},
+	}
+}
+
+// Averaging returns a Collector which averages the numbers produced by f, the result is 0 if no element.
+// the container is the pair of sum and count
+func Averaging[T any, N Number](f Function[T, N]) Collector[T, Pair[float64, int], float64] {
+	return Collector[T, Pair[float64, int], float64]{
+		Supplier: func() Pair[float64, int] {
+			return Pair[float64, int]{}
+		},
+		Accumulator: func(acc Pair[float64, int], e T) Pair[float64, int] {
+			return Pair[float64, int]{First: acc.First + float64(f(e)), Second: acc.Second + 1}
+		},
+		Combiner: func(left Pair[float64, int], right Pair[float64, int]) Pair[float64, int] {
+			return Pair[float64, int]{First: left.First + right.First, Second: left.Second + right.Second}
+		},
+		Finisher: func(acc Pair[float64, int]) float64 {
+			if acc.Second == 0 {
+				return 0
+			}
+			return acc.First / float64(acc.Second)
+		},
+	}
+}
+
+// Joining returns a Collector which joins elements formatted by %v with splitter
+func Joining[T any](split string) Collector[T, []string, string] {
+	return Collector[T, []string, string]{
+		Supplier: func() []string {
+			return make([]string, 0)
+		},
+		Accumulator: func(acc []string, e T) []string {
+			return append(acc, fmt.Sprintf("%v", e))
+		},
+		Combiner: func(left []string, right []string) []string {
+			return append(left, right...)
+		},
+		Finisher: func(acc []string) string {
+			return strings.Join(acc, split)
+		},
+	}
+}
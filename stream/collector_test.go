@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollect(t *testing.T) {
+	got := Collect(FromValues("a", "bb", "cc", "ddd"), Collector[string, map[int][]string, map[int]int]{
+		Supplier: func() map[int][]string {
+			return make(map[int][]string)
+		},
+		Accumulator: func(acc map[int][]string, e string) map[int][]string {
+			acc[len(e)] = append(acc[len(e)], e)
+			return acc
+		},
+		Finisher: func(acc map[int][]string) map[int]int {
+			ret := make(map[int]int)
+			for k, v := range acc {
+				ret[k] = len(v)
+			}
+			return ret
+		},
+	})
+	if !reflect.DeepEqual(got, map[int]int{1: 1, 2: 2, 3: 1}) {
+		t.Errorf("Collect() = %v", got)
+	}
+}
+
+func TestGroupingBy(t *testing.T) {
+	words := FromValues("apple", "avocado", "banana", "blueberry", "cherry", "apricot")
+	first := func(e string) byte {
+		return e[0]
+	}
+
+	if got := Collect(words, GroupingBy(first, Counting[string]())); !reflect.DeepEqual(got, map[byte]int{'a': 3, 'b': 2, 'c': 1}) {
+		t.Errorf("GroupingBy(Counting) = %v", got)
+	}
+	length := func(e string) int {
+		return len(e)
+	}
+	if got := Collect(words, GroupingBy(first, Summing(length))); !reflect.DeepEqual(got, map[byte]int{'a': 19, 'b': 15, 'c': 6}) {
+		t.Errorf("GroupingBy(Summing) = %v", got)
+	}
+	if got := Collect(words, GroupingBy(first, Averaging(length))); !reflect.DeepEqual(got, map[byte]float64{'a': 19.0 / 3, 'b': 7.5, 'c': 6}) {
+		t.Errorf("GroupingBy(Averaging) = %v", got)
+	}
+	upper := Mapping(strings.ToUpper, ToSlice[string]())
+	want := map[byte][]string{'a': {"APPLE", "AVOCADO", "APRICOT"}, 'b': {"BANANA", "BLUEBERRY"}, 'c': {"CHERRY"}}
+	if got := Collect(words, GroupingBy(first, upper)); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupingBy(Mapping) = %v", got)
+	}
+	if got := Collect(words, GroupingBy(first, Joining[string]("|"))); got['a'] != "apple|avocado|apricot" {
+		t.Errorf("GroupingBy(Joining) = %v", got)
+	}
+}
+
+func TestPartitioningBy(t *testing.T) {
+	even := func(e int) bool {
+		return e%2 == 0
+	}
+	got := Collect(FromValues(1, 3, 5), PartitioningBy(even, ToSlice[int]()))
+	if !reflect.DeepEqual(got, map[bool][]int{true: {}, false: {1, 3, 5}}) {
+		t.Errorf("PartitioningBy() = %v", got)
+	}
+}
+
+func TestToMap(t *testing.T) {
+	words := FromValues("a", "bb", "cc", "ddd")
+	length := func(e string) int {
+		return len(e)
+	}
+	self := func(e string) string {
+		return e
+	}
+	concat := func(e1 string, e2 string) string {
+		return e1 + e2
+	}
+	if got := Collect(words, ToMap(length, self, concat)); !reflect.DeepEqual(got, map[int]string{1: "a", 2: "bbcc", 3: "ddd"}) {
+		t.Errorf("ToMap() = %v", got)
+	}
+	if got := Collect(words, ToSet[string]()); !reflect.DeepEqual(got, map[string]bool{"a": true, "bb": true, "cc": true, "ddd": true}) {
+		t.Errorf("ToSet() = %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("ToMap() should panic on duplicate key without merge function")
+		}
+	}()
+	Collect(words, ToMap(length, self, nil))
+}
+
+func TestCollectParallel(t *testing.T) {
+	data := make([]int, 1000)
+	for i := range data {
+		data[i] = i
+	}
+	mod := func(e int) int {
+		return e % 7
+	}
+	seq := Collect(FromSlice(data), GroupingBy(mod, ToSlice[int]()))
+	par := Collect(FromSlice(data).Parallel(4), GroupingBy(mod, ToSlice[int]()))
+	if !reflect.DeepEqual(seq, par) {
+		t.Error("parallel GroupingBy() is not equal to sequential")
+	}
+	if got := Collect(FromSlice(data).Parallel(4), Joining[int]("")); got != Collect(FromSlice(data), Joining[int]("")) {
+		t.Error("parallel Joining() is not equal to sequential")
+	}
+}
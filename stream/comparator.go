@@ -0,0 +1,90 @@
+package stream
+
+// compare compares two ordered values without overflow. NaN is greater than any other number and equal to NaN
+func compare[K Ordered](left K, right K) int {
+	leftNaN, rightNaN := left != left, right != right
+	switch {
+	case leftNaN || rightNaN:
+		if leftNaN && rightNaN {
+			return 0
+		}
+		if leftNaN {
+			return 1
+		}
+		return -1
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
+
+// NaturalOrder returns a Comparator which compares ordered values by operator <
+func NaturalOrder[K Ordered]() Comparator[K] {
+	return compare[K]
+}
+
+// Comparing returns a Comparator which compares the keys extracted by key with cmp
+func Comparing[T, K any](key Function[T, K], cmp Comparator[K]) Comparator[T] {
+	return func(left T, right T) int {
+		return cmp(key(left), key(right))
+	}
+}
+
+// ComparingInt64 returns a Comparator which compares the int64 keys extracted by key, it never overflows
+func ComparingInt64[T any](key Function[T, int64]) Comparator[T] {
+	return Comparing(key, compare[int64])
+}
+
+// ComparingString returns a Comparator which compares the string keys extracted by key
+func ComparingString[T any](key Function[T, string]) Comparator[T] {
+	return Comparing(key, compare[string])
+}
+
+// ComparingFloat returns a Comparator which compares the float64 keys extracted by key, NaN is the greatest
+func ComparingFloat[T any](key Function[T, float64]) Comparator[T] {
+	return Comparing(key, compare[float64])
+}
+
+// ThenComparing returns a Comparator which compares by other when c returns 0
+func (c Comparator[T]) ThenComparing(other Comparator[T]) Comparator[T] {
+	return func(left T, right T) int {
+		if ret := c(left, right); ret != 0 {
+			return ret
+		}
+		return other(left, right)
+	}
+}
+
+// Reversed returns a Comparator which imposes the reverse order of c
+func (c Comparator[T]) Reversed() Comparator[T] {
+	return func(left T, right T) int {
+		return c(right, left)
+	}
+}
+
+// NullsFirst returns a Comparator of pointers, nil is less than non-nil and the pointed values are compared by cmp
+func NullsFirst[T any](cmp Comparator[T]) Comparator[*T] {
+	return nulls(cmp, -1)
+}
+
+// NullsLast returns a Comparator of pointers, nil is greater than non-nil and the pointed values are compared by cmp
+func NullsLast[T any](cmp Comparator[T]) Comparator[*T] {
+	return nulls(cmp, 1)
+}
+
+// nulls returns a Comparator of pointers, nil compares to non-nil as nilOrder
+func nulls[T any](cmp Comparator[T], nilOrder int) Comparator[*T] {
+	return func(left *T, right *T) int {
+		switch {
+		case left == nil && right == nil:
+			return 0
+		case left == nil:
+			return nilOrder
+		case right == nil:
+			return -nilOrder
+		}
+		return cmp(*left, *right)
+	}
+}
@@ -0,0 +1,97 @@
+package stream
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+type person struct {
+	Name string
+	Age  *int
+	ID   int64
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestComparingInt64(t *testing.T) {
+	cmp := ComparingInt64(func(e person) int64 {
+		return e.ID
+	})
+	// left.ID - right.ID overflows
+	if cmp(person{ID: math.MaxInt64}, person{ID: -2}) <= 0 {
+		t.Error("ComparingInt64() overflows")
+	}
+	if cmp(person{ID: math.MinInt64}, person{ID: 1}) >= 0 {
+		t.Error("ComparingInt64() overflows")
+	}
+	if cmp(person{ID: 7}, person{ID: 7}) != 0 {
+		t.Error("ComparingInt64() of equal keys should be 0")
+	}
+	nan := ComparingFloat(func(e float64) float64 {
+		return e
+	})
+	got := FromValues(2, math.NaN(), -1, math.Inf(1)).Sort(nan).ToSlice()
+	if got[0] != -1 || got[1] != 2 || !math.IsInf(got[2], 1) || !math.IsNaN(got[3]) {
+		t.Errorf("ComparingFloat() sorts %v", got)
+	}
+}
+
+func TestThenComparing(t *testing.T) {
+	people := FromValues(
+		person{Name: "bob", Age: intPtr(30), ID: 1},
+		person{Name: "amy", Age: nil, ID: 2},
+		person{Name: "bob", Age: intPtr(25), ID: 3},
+		person{Name: "amy", Age: intPtr(40), ID: 4},
+		person{Name: "cat", Age: nil, ID: 5},
+	)
+	ids := func(s Stream[person]) []int64 {
+		return Map(s, func(e person) int64 {
+			return e.ID
+		}).ToSlice()
+	}
+	age := func(e person) *int {
+		return e.Age
+	}
+	byName := ComparingString(func(e person) string {
+		return e.Name
+	})
+
+	cmp := byName.ThenComparing(Comparing(age, NullsFirst(NaturalOrder[int]())))
+	if got := ids(people.Sort(cmp)); !reflect.DeepEqual(got, []int64{2, 4, 3, 1, 5}) {
+		t.Errorf("ThenComparing() sorts %v", got)
+	}
+	cmp = byName.Reversed().ThenComparing(Comparing(age, NullsLast(NaturalOrder[int]())))
+	if got := ids(people.Sort(cmp)); !reflect.DeepEqual(got, []int64{5, 3, 1, 4, 2}) {
+		t.Errorf("Reversed() sorts %v", got)
+	}
+	if got := ids(people.SortStable(byName)); !reflect.DeepEqual(got, []int64{2, 4, 1, 3, 5}) {
+		t.Errorf("SortStable() sorts %v", got)
+	}
+	if got := ids(people.Parallel(2).SortStable(byName)); !reflect.DeepEqual(got, []int64{2, 4, 1, 3, 5}) {
+		t.Errorf("parallel SortStable() sorts %v", got)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	calls := 0
+	length := func(e string) int {
+		calls++
+		return len(e)
+	}
+	words := FromValues("ccc", "a", "bb", "dd", "e", "ffff")
+	if got := SortBy(words, length).ToSlice(); !reflect.DeepEqual(got, []string{"a", "e", "bb", "dd", "ccc", "ffff"}) {
+		t.Errorf("SortBy() = %v", got)
+	}
+	if calls != 6 {
+		t.Errorf("key computed %v times, want 6", calls)
+	}
+	strlen := func(e string) int {
+		return len(e)
+	}
+	if got := SortBy(words.Parallel(3), strlen).Limit(3).ToSlice(); !reflect.DeepEqual(got, []string{"a", "e", "bb"}) {
+		t.Errorf("parallel SortBy() = %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// runConcurrently runs f with many goroutines at the same time, run with -race to detect the shared state
+func runConcurrently(t *testing.T, f func() error) {
+	const goroutines = 16
+	wg := sync.WaitGroup{}
+	errs := make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if err := f(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// mismatch is the error of an unexpected result
+type mismatch struct {
+	got  interface{}
+	want interface{}
+}
+
+func (m mismatch) Error() string {
+	return fmt.Sprintf("got %v, want %v", m.got, m.want)
+}
+
+// expect returns a function which checks the result of f is want
+func expect[E any](f func() E, want E) func() error {
+	return func() error {
+		if got := f(); !reflect.DeepEqual(got, want) {
+			return mismatch{got: got, want: want}
+		}
+		return nil
+	}
+}
+
+func TestConcurrentLimitSkip(t *testing.T) {
+	s := Iterate(0, func(e int) int { return e + 1 }).Skip(3).Limit(5)
+	runConcurrently(t, expect(s.Count, 5))
+	runConcurrently(t, expect(s.ToSlice, []int{3, 4, 5, 6, 7}))
+	sliced := FromValues(1, 2, 3, 4, 5, 6).Slice(2, 3)
+	runConcurrently(t, expect(func() string { return sliced.Join(",") }, "3,4,5"))
+}
+
+func TestConcurrentZipInterleave(t *testing.T) {
+	zipped := FromValues(1, 2, 3).ZipAll(FromValues(10, 20), 0, func(e1 int, e2 int) int {
+		return e1 + e2
+	})
+	runConcurrently(t, expect(zipped.ToSlice, []int{11, 22, 3}))
+	interleaved := FromValues(1, 3, 5).Interleave(FromValues(2, 4))
+	runConcurrently(t, expect(interleaved.ToSlice, []int{1, 2, 3, 4, 5}))
+}
+
+func TestConcurrentWindowed(t *testing.T) {
+	windows := Windowed(FromValues(1, 2, 3, 4), 2, 1, false)
+	runConcurrently(t, expect(windows.ToSlice, [][]int{{1, 2}, {2, 3}, {3, 4}}))
+	chunks := Of(1, 2, 3).Chunk(2)
+	runConcurrently(t, expect(chunks.Count, 2))
+}
+
+func TestConcurrentParallel(t *testing.T) {
+	list := make([]int, 1000)
+	for i := range list {
+		list[i] = i
+	}
+	s := FromSlice(list).Parallel(4).Filter(func(e int) bool {
+		return e%2 == 0
+	}).Sort(func(left int, right int) int {
+		return right - left
+	}).Limit(3)
+	runConcurrently(t, expect(s.ToSlice, []int{998, 996, 994}))
+	counted := FromSlice(list).Parallel(4).Skip(10).Map(func(e int) int {
+		return e * 2
+	})
+	runConcurrently(t, expect(counted.Count, 990))
+}
+
+func TestConcurrentTerminals(t *testing.T) {
+	// the terminal operations don't modify the stream
+	s := FromValues(3, 1, 2).Limit(2)
+	runConcurrently(t, func() error {
+		if got, _ := s.Max(NaturalOrder[int]()).Value(); got != 3 {
+			return mismatch{got: got, want: 3}
+		}
+		if got := Collect(s, ToSlice[int]()); !reflect.DeepEqual(got, []int{3, 1}) {
+			return mismatch{got: got, want: []int{3, 1}}
+		}
+		if got := s.AnyMatch(func(e int) bool { return e == 2 }); got {
+			return mismatch{got: got, want: false}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package stream
+
+import "fmt"
+
+// StageError is the error of a failed execution, which locates the operation and the element
+type StageError struct {
+	Stage string // Stage is the name of operation which fails, it is Source if the source fails
+	// Index is the index of element received by the operation, -1 if the operation receives all elements at once.
+	// the elements are counted from the upstream of operation, not the source
+	Index int
+	Err   error // Err is the error returned by the function, or recovered from its panic
+}
+
+func (e *StageError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("stream: %s failed: %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("stream: %s failed at element %d: %v", e.Stage, e.Index, e.Err)
+}
+
+// Unwrap returns the error of function
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// errorAt returns the error of element at index, which is returned by the actions receive all elements at once.
+// the stage machine fills the name of operation
+func errorAt(index int, err error) error {
+	return &StageError{Index: index, Err: err}
+}
+
+// recovered converts the value recovered from panic to error.
+// the error of a nested stream, like the one produced by FlatMap, is kept as it is
+func recovered(r interface{}) error {
+	switch err := r.(type) {
+	case *StageError:
+		return err
+	case error:
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", r)
+}
@@ -0,0 +1,167 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMapE(t *testing.T) {
+	parsed := 0
+	parse := func(e string) (int, error) {
+		parsed++
+		return strconv.Atoi(e)
+	}
+	got, err := MapE(FromValues("1", "2", "x", "4"), parse).ReduceE(func(acc int, e int, i int, n int) (int, error) {
+		return acc + e, nil
+	}, 0)
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != "MapE" || se.Index != 2 {
+		t.Fatalf("ReduceE() = %v, %v", got, err)
+	}
+	var numErr *strconv.NumError
+	if !errors.As(err, &numErr) {
+		t.Errorf("error %v should wrap the error of function", err)
+	}
+	if parsed != 3 {
+		t.Errorf("parsed %v elements, the stream should stop at the error", parsed)
+	}
+
+	// the infinite source is stopped by the error
+	_, err = Iterate(1, func(e int) int { return e + 1 }).MapE(func(e int) (int, error) {
+		if e == 5 {
+			return 0, errors.New("five")
+		}
+		return e, nil
+	}).Sort(NaturalOrder[int]()).ReduceE(func(acc int, e int, i int, n int) (int, error) {
+		return acc + e, nil
+	}, 0)
+	if err == nil || err.Error() != "stream: MapE failed at element 4: five" {
+		t.Errorf("ReduceE() error = %v", err)
+	}
+
+	got, err = MapE(FromValues("1", "2"), parse).ReduceE(func(acc int, e int, i int, n int) (int, error) {
+		return acc + e, nil
+	}, 0)
+	if got != 3 || err != nil {
+		t.Errorf("ReduceE() = %v, %v", got, err)
+	}
+}
+
+func TestFilterE(t *testing.T) {
+	consumed := make([]int, 0)
+	err := FromValues(1, 2, 3, 4).FilterE(func(e int) (bool, error) {
+		if e == 3 {
+			return false, errors.New("three")
+		}
+		return e%2 == 0, nil
+	}).ForEachE(func(e int) error {
+		consumed = append(consumed, e)
+		return nil
+	})
+	if err == nil || err.Error() != "stream: FilterE failed at element 2: three" {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+	// the elements before the error are consumed
+	if !reflect.DeepEqual(consumed, []int{2}) {
+		t.Errorf("consumed %v", consumed)
+	}
+}
+
+func TestForEachE(t *testing.T) {
+	consumed := 0
+	err := Generate(func() int { return 1 }).ForEachE(func(e int) error {
+		if consumed++; consumed == 3 {
+			return errors.New("enough")
+		}
+		return nil
+	})
+	if err == nil || err.Error() != "stream: ForEachE failed at element 2: enough" {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+	if err := FromValues(1, 2).ForEachE(func(e int) error { return nil }); err != nil {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+}
+
+func TestReduceE(t *testing.T) {
+	_, err := FromValues(1, 2, 3).ReduceE(func(acc int, e int, i int, n int) (int, error) {
+		if e == 2 {
+			return 0, errors.New("two")
+		}
+		return acc + e, nil
+	}, 0)
+	if err == nil || err.Error() != "stream: ReduceE failed at element 1: two" {
+		t.Errorf("ReduceE() error = %v", err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	// the panic of function is recovered into the error of terminal operation
+	err := FromValues(1, 2, 0, 4).Map(func(e int) int {
+		return 12 / e
+	}).ForEachE(func(e int) error { return nil })
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != "Map" || se.Index != 2 {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+
+	// the error of nested stream is reported by the operation which executes it
+	err = FromValues(1, 2).FlatMap(func(e int) Stream[int] {
+		return FromValues(e, 0).MapE(func(e int) (int, error) {
+			if e == 0 {
+				return 0, errors.New("zero")
+			}
+			return e, nil
+		})
+	}).ForEachE(func(e int) error { return nil })
+	if err == nil || err.Error() != "stream: FlatMap failed at element 0: stream: MapE failed at element 1: zero" {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+
+	// the stateful operations are located by name
+	err = FromValues(2, 1).Sort(func(left int, right int) int {
+		panic("no order")
+	}).ForEachE(func(e int) error { return nil })
+	if err == nil || err.Error() != "stream: Sort failed: panic: no order" {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+
+	// the operations without error panic with the error
+	defer func() {
+		if _, ok := recover().(*StageError); !ok {
+			t.Error("Count() should panic with *StageError")
+		}
+	}()
+	FromValues(1).MapE(func(e int) (int, error) {
+		return 0, errors.New("fail")
+	}).Count()
+}
+
+func TestErrorParallel(t *testing.T) {
+	list := make([]int, 1000)
+	for i := range list {
+		list[i] = i
+	}
+	err := FromSlice(list).Parallel(4).MapE(func(e int) (int, error) {
+		if e == 600 {
+			return 0, errors.New("600")
+		}
+		return e, nil
+	}).ForEachE(func(e int) error { return nil })
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != "MapE" || se.Index != 600 {
+		t.Errorf("ForEachE() error = %v", err)
+	}
+
+	// the panic in workers is recovered too
+	_, err = FromSlice(list).Parallel(4).Filter(func(e int) bool {
+		return list[e+1] > 0
+	}).ReduceE(func(acc int, e int, i int, n int) (int, error) {
+		return acc + e, nil
+	}, 0)
+	if !errors.As(err, &se) || se.Stage != "Filter" || se.Index != 999 {
+		t.Errorf("ReduceE() error = %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"fmt"
+	"strings"
+)
+
+// plan returns the groups of stages executed by the stream, and whether each group executes parallel
+func (s *stream[E]) plan() ([][]stage, []bool) {
+	opts := append([]stage{}, s.opts...)
+	if !s.unoptimized {
+		opts = optimize(opts)
+	}
+	groups := group(opts, s.para > 0)
+	para := make([]bool, len(groups))
+	for i, g := range groups {
+		if s.para == 0 {
+			continue
+		}
+		if g[0].stageFlag == stageStateless {
+			para[i] = parallelizable(g)
+		} else {
+			para[i] = g[0].parallel != nil
+		}
+	}
+	return groups, para
+}
+
+// source returns the description of source and execution mode
+func (s *stream[E]) source() string {
+	ret := "Source"
+	if s.para > 0 {
+		ret += fmt.Sprintf(" (%d workers", s.para)
+		if s.unordered {
+			ret += ", unordered"
+		}
+		ret += ")"
+	}
+	return ret
+}
+
+// Explain returns the execution plan of stream, one group of stages per line.
+// the adjacent stateless operations are fused in one group, which pulls elements one by one,
+// and each stateful operation is a group alone, which receives all elements of upstream.
+// when the stream executes parallel, the stateless operations which have state, like Limit and Skip,
+// are split from the others, so the others are still executed in parallel
+func (s *stream[E]) Explain() string {
+	groups, para := s.plan()
+	sb := &strings.Builder{}
+	sb.WriteString(s.source())
+	sb.WriteString("\n")
+	for i, g := range groups {
+		names := make([]string, len(g))
+		for j, sg := range g {
+			names[j] = sg.name
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s: %s", i+1, stageFlagNames[g[0].stageFlag], strings.Join(names, " -> ")))
+		if s.para > 0 {
+			if para[i] {
+				sb.WriteString(" (parallel)")
+			} else {
+				sb.WriteString(" (sequential)")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ExplainDOT returns the execution plan of stream in Graphviz DOT language, each group is a cluster
+func (s *stream[E]) ExplainDOT() string {
+	groups, para := s.plan()
+	sb := &strings.Builder{}
+	sb.WriteString("digraph stream {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	sb.WriteString(fmt.Sprintf("\tsource [label=%q];\n", s.source()))
+	prev := "source"
+	for i, g := range groups {
+		label := fmt.Sprintf("%d. %s", i+1, stageFlagNames[g[0].stageFlag])
+		if para[i] {
+			label += " (parallel)"
+		}
+		sb.WriteString(fmt.Sprintf("\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i+1, label))
+		edges := make([]string, 0, len(g))
+		for j, sg := range g {
+			node := fmt.Sprintf("s%d_%d", i+1, j+1)
+			sb.WriteString(fmt.Sprintf("\t\t%s [label=%q];\n", node, sg.name))
+			edges = append(edges, fmt.Sprintf("\t%s -> %s;\n", prev, node))
+			prev = node
+		}
+		sb.WriteString("\t}\n")
+		sb.WriteString(strings.Join(edges, ""))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+package stream
+
+import "testing"
+
+func TestExplain(t *testing.T) {
+	s := FromValues(3, 1, 2).Filter(func(e int) bool {
+		return e > 0
+	}).Map(func(e int) int {
+		return e * 2
+	}).Sort(NaturalOrder[int]()).Limit(2).Optimize(false)
+	want := "Source\n" +
+		"1. stateless: Filter -> Map\n" +
+		"2. stateful: Sort\n" +
+		"3. stateless: Limit\n"
+	if got := s.Explain(); got != want {
+		t.Errorf("Explain() = %v, want %v", got, want)
+	}
+
+	want = "Source (4 workers, unordered)\n" +
+		"1. stateless: Filter -> Map (parallel)\n" +
+		"2. stateful: Sort (parallel)\n" +
+		"3. stateless: Limit (sequential)\n"
+	if got := s.Parallel(4).Unordered().Explain(); got != want {
+		t.Errorf("Explain() = %v, want %v", got, want)
+	}
+
+	// the stages which have state are split from the parallel ones
+	want = "Source (4 workers)\n" +
+		"1. stateless: Skip (sequential)\n" +
+		"2. stateless: Filter -> Map (parallel)\n" +
+		"3. stateless: Limit (sequential)\n"
+	if got := FromValues(3, 1, 2).Parallel(4).Skip(1).Filter(func(e int) bool {
+		return e > 0
+	}).Map(func(e int) int {
+		return e * 2
+	}).Limit(2).Explain(); got != want {
+		t.Errorf("Explain() = %v, want %v", got, want)
+	}
+
+	// the optimized plan
+	want = "Source\n" +
+		"1. stateless: Filter -> Map\n" +
+		"2. stateful: TopK\n"
+	if got := s.Optimize(true).Explain(); got != want {
+		t.Errorf("Explain() = %v, want %v", got, want)
+	}
+
+	if got := FromValues(1).Explain(); got != "Source\n" {
+		t.Errorf("Explain() = %v", got)
+	}
+}
+
+func TestExplainDOT(t *testing.T) {
+	s := FromValues(3, 1, 2).Skip(1).Map(func(e int) int {
+		return e * 2
+	}).Reverse()
+	want := `digraph stream {
+	rankdir=LR;
+	node [shape=box];
+	source [label="Source"];
+	subgraph cluster_1 {
+		label="1. stateless";
+		s1_1 [label="Skip"];
+		s1_2 [label="Map"];
+	}
+	source -> s1_1;
+	s1_1 -> s1_2;
+	subgraph cluster_2 {
+		label="2. stateful";
+		s2_1 [label="Reverse"];
+	}
+	s1_2 -> s2_1;
+}
+`
+	if got := s.ExplainDOT(); got != want {
+		t.Errorf("ExplainDOT() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,341 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// getter reads a field from v, false if it is absent, like a nil pointer on the path or a missing key of map
+type getter func(v reflect.Value) (reflect.Value, bool)
+
+type fieldKey struct {
+	t    reflect.Type
+	path string
+}
+
+type compiled struct {
+	get getter
+	err error
+}
+
+// getters caches the compiled getters by type and path
+var getters sync.Map
+
+// accessor returns the cached getter of path from a value of type t, it is compiled at the first call.
+// it returns the error if t has no such field
+func accessor(t reflect.Type, path string) (getter, error) {
+	key := fieldKey{t: t, path: path}
+	if c, ok := getters.Load(key); ok {
+		return c.(compiled).get, c.(compiled).err
+	}
+	var names []string
+	if path != "" {
+		names = strings.Split(path, ".")
+	}
+	get, err := compile(t, names)
+	if err != nil {
+		err = fmt.Errorf("stream: no field %q in %v: %w", path, t, err)
+	}
+	getters.Store(key, compiled{get: get, err: err})
+	return get, err
+}
+
+// cachedAccessor keeps the getter of path for the last type read, so reading the values of the same type
+// costs one comparison of types. it is safe for concurrent use
+type cachedAccessor struct {
+	path string
+	last atomic.Value // last is the *typedGetter of the last type
+}
+
+type typedGetter struct {
+	t reflect.Type
+	compiled
+}
+
+// accessor returns the getter of path from a value of type t, like the function accessor
+func (c *cachedAccessor) accessor(t reflect.Type) (getter, error) {
+	if last, _ := c.last.Load().(*typedGetter); last != nil && last.t == t {
+		return last.get, last.err
+	}
+	get, err := accessor(t, c.path)
+	c.last.Store(&typedGetter{t: t, compiled: compiled{get: get, err: err}})
+	return get, err
+}
+
+// compile compiles the getter of names from a value of type t. the pointers on the path are dereferenced,
+// the maps with string keys are indexed by name, and the interfaces are compiled by their dynamic types when read
+func compile(t reflect.Type, names []string) (getter, error) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		next, err := compile(t.Elem(), names)
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (reflect.Value, bool) {
+			if v.IsNil() {
+				return v, false
+			}
+			return next(v.Elem())
+		}, nil
+	case reflect.Interface:
+		// the rest of path is schemaless, so it is absent if the dynamic type has no such field
+		cache := &cachedAccessor{path: strings.Join(names, ".")}
+		return func(v reflect.Value) (reflect.Value, bool) {
+			if v.IsNil() {
+				return v, false
+			}
+			get, err := cache.accessor(v.Elem().Type())
+			if err != nil {
+				return v, false
+			}
+			return get(v.Elem())
+		}, nil
+	}
+	if len(names) == 0 {
+		return func(v reflect.Value) (reflect.Value, bool) {
+			return v, true
+		}, nil
+	}
+
+	name := names[0]
+	switch {
+	case t.Kind() == reflect.Struct:
+		f, ok := t.FieldByName(name)
+		if !ok || f.PkgPath != "" {
+			return nil, fmt.Errorf("%v has no exported field %s", t, name)
+		}
+		next, err := compile(f.Type, names[1:])
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (reflect.Value, bool) {
+			// the embedded structs on the path may be nil pointers
+			field, err := v.FieldByIndexErr(f.Index)
+			if err != nil {
+				return v, false
+			}
+			return next(field)
+		}, nil
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		k := reflect.ValueOf(name).Convert(t.Key())
+		next, err := compile(t.Elem(), names[1:])
+		if err != nil {
+			return nil, err
+		}
+		return func(v reflect.Value) (reflect.Value, bool) {
+			e := v.MapIndex(k)
+			if !e.IsValid() {
+				return e, false
+			}
+			return next(e)
+		}, nil
+	}
+	return nil, fmt.Errorf("%v has no field %s", t, name)
+}
+
+// Field returns a Function which reads the field of path from an element, the names of path are separated by dot,
+// like "Position.City". the pointers on the path are dereferenced, so the result is the value of field,
+// it is nil if the element or any pointer on the path is nil. the maps with string keys are indexed by the names.
+// the path is compiled once for each type of element and cached, and the Function keeps the getter of the last type.
+// the Function panics if the element has no such field. it maps the elements of Untyped streams, use FieldOf for
+// the typed streams
+func Field(path string) Function[T, R] {
+	cache := &cachedAccessor{path: path}
+	return func(e T) R {
+		if e == nil {
+			return nil
+		}
+		get, err := cache.accessor(reflect.TypeOf(e))
+		if err != nil {
+			panic(err)
+		}
+		if ret, ok := get(reflect.ValueOf(e)); ok {
+			return ret.Interface()
+		}
+		return nil
+	}
+}
+
+// FieldOf is the typed Field, which reads the field of path from an element of E as R, so it maps the typed streams,
+// like Map(FromSlice(employees), FieldOf[Employee, string]("Position.City")). the result is the zero value of R
+// if the field is nil, and the Function panics if the field is not an R
+func FieldOf[E, R any](path string) Function[E, R] {
+	get := Field(path)
+	return func(e E) R {
+		return as[R](get(e))
+	}
+}
+
+// compareValues compares two values of the same kind, the integers and floats of any size are compared by value.
+// it returns the error if they are not comparable
+func compareValues(left T, right T) (int, error) {
+	l, r := reflect.ValueOf(left), reflect.ValueOf(right)
+	switch lk, rk := kindOf(l), kindOf(r); {
+	case lk == reflect.Float64 && (rk == reflect.Float64 || rk == reflect.Int64 || rk == reflect.Uint64) ||
+		rk == reflect.Float64 && (lk == reflect.Int64 || lk == reflect.Uint64):
+		return compare(toFloat(l), toFloat(r)), nil
+	case lk == reflect.Int64 && rk == reflect.Int64:
+		return compare(l.Int(), r.Int()), nil
+	case lk == reflect.Uint64 && rk == reflect.Uint64:
+		return compare(l.Uint(), r.Uint()), nil
+	case lk == reflect.Int64 && rk == reflect.Uint64:
+		if l.Int() < 0 {
+			return -1, nil
+		}
+		return compare(uint64(l.Int()), r.Uint()), nil
+	case lk == reflect.Uint64 && rk == reflect.Int64:
+		if r.Int() < 0 {
+			return 1, nil
+		}
+		return compare(l.Uint(), uint64(r.Int())), nil
+	case lk == reflect.String && rk == reflect.String:
+		return compare(l.String(), r.String()), nil
+	case lk == reflect.Bool && rk == reflect.Bool:
+		return compare(boolToInt(l.Bool()), boolToInt(r.Bool())), nil
+	}
+	return 0, fmt.Errorf("stream: can't compare %T with %T", left, right)
+}
+
+// kindOf returns the kind of v, the integers are Int64 or Uint64 and the floats are Float64
+func kindOf(v reflect.Value) reflect.Kind {
+	switch k := v.Kind(); k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int64
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return reflect.Uint64
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	default:
+		return k
+	}
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch kindOf(v) {
+	case reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return v.Float()
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compareNullsLast compares two values by compareValues, nil is greater than non-nil. it panics if they are not comparable
+func compareNullsLast(left T, right T) int {
+	switch {
+	case left == nil && right == nil:
+		return 0
+	case left == nil:
+		return 1
+	case right == nil:
+		return -1
+	}
+	ret, err := compareValues(left, right)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// matches returns a Predicate which tests the value compared to v by ok, nil never matches.
+// the numbers of any type are compared by value, the values of different kinds never match
+func matches(v T, ok func(c int) bool) Predicate[T] {
+	return func(e T) bool {
+		if e == nil || v == nil {
+			return false
+		}
+		c, err := compareValues(e, v)
+		return err == nil && ok(c)
+	}
+}
+
+// Eq returns a Predicate which tests if the value is equal to v, the uncomparable values are tested by reflect.DeepEqual.
+// nil is only equal to nil
+func Eq(v T) Predicate[T] {
+	return func(e T) bool {
+		if e == nil || v == nil {
+			return e == nil && v == nil
+		}
+		if c, err := compareValues(e, v); err == nil {
+			return c == 0
+		}
+		return reflect.DeepEqual(e, v)
+	}
+}
+
+// Ne returns a Predicate which tests if the value is not equal to v
+func Ne(v T) Predicate[T] {
+	eq := Eq(v)
+	return func(e T) bool {
+		return !eq(e)
+	}
+}
+
+// Gt returns a Predicate which tests if the value is greater than v, nil never matches
+func Gt(v T) Predicate[T] {
+	return matches(v, func(c int) bool { return c > 0 })
+}
+
+// Ge returns a Predicate which tests if the value is greater than or equal to v, nil never matches
+func Ge(v T) Predicate[T] {
+	return matches(v, func(c int) bool { return c >= 0 })
+}
+
+// Lt returns a Predicate which tests if the value is less than v, nil never matches
+func Lt(v T) Predicate[T] {
+	return matches(v, func(c int) bool { return c < 0 })
+}
+
+// Le returns a Predicate which tests if the value is less than or equal to v, nil never matches
+func Le(v T) Predicate[T] {
+	return matches(v, func(c int) bool { return c <= 0 })
+}
+
+// FilterField filters out if the fields of path of elements match the condition, see Field for the path
+func (s *stream[E]) FilterField(path string, f Predicate[T]) Stream[E] {
+	get := Field(path)
+	ret := s.Filter(func(e E) bool {
+		return f(get(e))
+	}).(*stream[E])
+	ret.opts[len(ret.opts)-1].name = "FilterField"
+	return ret
+}
+
+// SortByField sorts elements by the fields of path in ascending order, the equal elements keep their encounter order
+// and nil is the greatest. the numbers are compared by value, strings and bools are compared too,
+// the execution fails if the fields are not comparable. see Field for the path
+func (s *stream[E]) SortByField(path string) Stream[E] {
+	get := Field(path)
+	return s.sort(func(left E, right E) int {
+		return compareNullsLast(get(left), get(right))
+	}, true)
+}
+
+// GroupByField groups elements by the fields of path, the elements of each group keep their encounter order
+// and the elements whose field is nil are grouped by nil. the fields must be comparable, see Field for the path
+func (s *stream[E]) GroupByField(path string) (ret map[T][]E) {
+	get := Field(path)
+	s.terminate(stage{
+		name: "GroupByField",
+		action: func(ele T, i int) (R, int) {
+			ret = make(map[T][]E)
+			for _, e := range ele.([]T) {
+				k := get(e)
+				ret[k] = append(ret[k], as[E](e))
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return
+}
@@ -0,0 +1,155 @@
+package stream
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type city struct {
+	Name *string
+}
+
+type employee struct {
+	Id   int64
+	Age  *int
+	City *city
+	name string
+}
+
+func ptr[E any](e E) *E {
+	return &e
+}
+
+func TestField(t *testing.T) {
+	alice := employee{Id: 1, Age: ptr(30), City: &city{Name: ptr("Paris")}}
+	cityName := Field("City.Name")
+	if got := cityName(alice); got != "Paris" {
+		t.Errorf("Field() = %v, want Paris", got)
+	}
+	if got := cityName(&alice); got != "Paris" {
+		t.Errorf("Field() of pointer = %v, want Paris", got)
+	}
+	// nil on the path is nil
+	for _, e := range []T{employee{}, employee{City: &city{}}, (*employee)(nil), nil} {
+		if got := cityName(e); got != nil {
+			t.Errorf("Field() of %#v = %v, want nil", e, got)
+		}
+	}
+	if got := Field("Age")(alice); got != 30 {
+		t.Errorf("Field() = %v, want 30", got)
+	}
+
+	// the maps are indexed by the names, the missing keys are nil
+	record := map[string]interface{}{"city": map[string]interface{}{"name": "Rome"}}
+	if got := Field("city.name")(record); got != "Rome" {
+		t.Errorf("Field() of map = %v, want Rome", got)
+	}
+	if got := Field("city.zip")(record); got != nil {
+		t.Errorf("Field() of missing key = %v, want nil", got)
+	}
+
+	for _, path := range []string{"Zip", "name", "Age.Value"} {
+		_, err := accessor(reflect.TypeOf(alice), path)
+		if err == nil || !strings.Contains(err.Error(), path) {
+			t.Errorf("accessor(%q) error = %v", path, err)
+		}
+	}
+	defer func() {
+		if err, ok := recover().(error); !ok || !strings.Contains(err.Error(), "Zip") {
+			t.Errorf("Field() of unknown field should panic with the error, got %v", err)
+		}
+	}()
+	Field("Zip")(alice)
+}
+
+func TestFieldOf(t *testing.T) {
+	employees := []employee{
+		{Id: 1, City: &city{Name: ptr("Paris")}},
+		{Id: 2},
+		{Id: 3, City: &city{Name: ptr("Rome")}},
+	}
+	// the typed streams are mapped without Untyped, nil is the zero value
+	if got := Map(FromSlice(employees), FieldOf[employee, string]("City.Name")).ToSlice(); !reflect.DeepEqual(got, []string{"Paris", "", "Rome"}) {
+		t.Errorf("Map(FieldOf()) = %v", got)
+	}
+	if got := Map(FromValues(&employees[0]), FieldOf[*employee, int64]("Id")).ToSlice(); !reflect.DeepEqual(got, []int64{1}) {
+		t.Errorf("Map(FieldOf()) of pointers = %v", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("FieldOf() of another type doesn't panic")
+		}
+	}()
+	FieldOf[employee, string]("Id")(employees[0])
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		left, right T
+		want        int
+	}{
+		{1, 2, -1},
+		{int64(3), 3, 0},
+		{uint8(200), -1, 1},
+		{-1, uint(0), -1},
+		{2.5, 2, 1},
+		{"a", "b", -1},
+		{true, false, 1},
+	}
+	for _, tt := range tests {
+		if got, err := compareValues(tt.left, tt.right); got != tt.want || err != nil {
+			t.Errorf("compareValues(%v, %v) = %v, %v, want %v", tt.left, tt.right, got, err, tt.want)
+		}
+	}
+	if _, err := compareValues(1, "1"); err == nil {
+		t.Error("compareValues() of int and string should fail")
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	if !Gt(22)(int64(30)) || Gt(22)(nil) || Gt(22)("30") || !Lt(1)(0.5) || !Ge(2)(2) || !Le("b")("a") {
+		t.Error("comparisons are wrong")
+	}
+	if !Eq(nil)(nil) || Eq(nil)(0) || !Eq(3)(3.0) || !Eq([]int{1})([]int{1}) || !Ne(1)(nil) {
+		t.Error("equalities are wrong")
+	}
+}
+
+func TestFieldOperations(t *testing.T) {
+	people := FromValues(
+		employee{Id: 3, Age: ptr(40)},
+		employee{Id: 1, Age: ptr(20)},
+		employee{Id: 4},
+		employee{Id: 2, Age: ptr(40)},
+	)
+	ids := func(list []employee) []int64 {
+		ret := make([]int64, len(list))
+		for i, e := range list {
+			ret[i] = e.Id
+		}
+		return ret
+	}
+	if got := ids(people.FilterField("Age", Gt(22)).ToSlice()); !reflect.DeepEqual(got, []int64{3, 2}) {
+		t.Errorf("FilterField() = %v", got)
+	}
+	// nil is the greatest, the equal ones keep their order
+	if got := ids(people.SortByField("Age").ToSlice()); !reflect.DeepEqual(got, []int64{1, 3, 2, 4}) {
+		t.Errorf("SortByField() = %v", got)
+	}
+	if got := ids(people.Parallel(2).SortByField("Id").ToSlice()); !reflect.DeepEqual(got, []int64{1, 2, 3, 4}) {
+		t.Errorf("parallel SortByField() = %v", got)
+	}
+	groups := people.GroupByField("Age")
+	if len(groups) != 3 || !reflect.DeepEqual(ids(groups[40]), []int64{3, 2}) || !reflect.DeepEqual(ids(groups[nil]), []int64{4}) {
+		t.Errorf("GroupByField() = %v", groups)
+	}
+
+	// the execution fails if the fields are not comparable
+	_, err := FromValues[T](1, "a").SortByField("").ToSliceE()
+	var se *StageError
+	if !errors.As(err, &se) || se.Stage != "SortStable" {
+		t.Errorf("SortByField() error = %v", err)
+	}
+}
@@ -2,15 +2,19 @@
 package stream
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type sortable struct {
 	List []T
-	Cmp  Comparator
+	Cmp  Comparator[T]
 }
 
 func (a *sortable) Len() int {
@@ -25,223 +29,496 @@ func (a *sortable) Swap(i, j int) {
 	a.List[i], a.List[j] = a.List[j], a.List[i]
 }
 
-type stream struct {
-	data []T     // data is the source of stream
-	opts []stage // opts is the operations of stream
-	para uint32  // para is the atomic field, which flag the stream execute parallel
+// sortSlice sorts the slice, the equal elements keep their order if stable
+func sortSlice(list []T, cmp Comparator[T], stable bool) {
+	a := &sortable{
+		List: list,
+		Cmp:  cmp,
+	}
+	if stable {
+		sort.Stable(a)
+	} else {
+		sort.Sort(a)
+	}
+}
+
+// parallelSort sorts each chunk by workers, then merges the sorted chunks in pairs.
+// merge keeps the order of equal elements, so it is stable if the chunks are sorted stable.
+// the workers stop when stopped is true, and the result is incomplete then
+func parallelSort(chunks [][]T, cmp Comparator[T], workers int, stable bool, stopped func() bool) []T {
+	runWorkers(workers, len(chunks), func(i int) {
+		if stopped() {
+			return
+		}
+		sortSlice(chunks[i], cmp, stable)
+	})
+	for len(chunks) > 1 && !stopped() {
+		merged := make([][]T, (len(chunks)+1)/2)
+		runWorkers(workers, len(merged), func(i int) {
+			if 2*i+1 == len(chunks) || stopped() {
+				merged[i] = chunks[2*i]
+				return
+			}
+			merged[i] = merge(chunks[2*i], chunks[2*i+1], cmp)
+		})
+		chunks = merged
+	}
+	if len(chunks) == 0 {
+		return []T{}
+	}
+	return chunks[0]
+}
+
+// merge merges two sorted slices to a new sorted slice
+func merge(left []T, right []T, cmp Comparator[T]) []T {
+	ret := make([]T, 0, len(left)+len(right))
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		if cmp(right[j], left[i]) < 0 {
+			ret = append(ret, right[j])
+			j++
+		} else {
+			ret = append(ret, left[i])
+			i++
+		}
+	}
+	ret = append(ret, left[i:]...)
+	return append(ret, right[j:]...)
+}
+
+// as converts an element of the stage machine to E.
+// nil is converted to the zero value of E, and it panics if the element is not an E
+func as[E any](e T) E {
+	if e == nil {
+		var zero E
+		return zero
+	}
+	return e.(E)
+}
+
+type stream[E any] struct {
+	src       func() iterator // src is the source of stream, which creates a new iterator for each execution
+	opts      []stage         // opts is the operations of stream
+	para      uint32          // para is the number of workers, the stream executes parallel if it is not 0
+	unordered bool            // unordered allows parallel execution ignore the encounter order
+	ctx       context.Context // ctx cancels the execution, the stream can't be canceled if it is nil
+	hook      Hook            // hook receives the report of each execution, the stream is not instrumented if it is nil
+	// unoptimized executes the operations as they are, the optimizer rewrites them to the faster ones if it is false
+	unoptimized bool
 
 	// prod []R     // prod is the product of stream
 	// prev Stream  // prev is the stream state, the stream is head if this field is nil
 	// wrap wrapper // wrap is the wrapper of the next stream, which will recurves call in terminal
 }
 
-func (s *stream) getStageMachine() *stageMachine {
+// getStageMachine creates the stage machine of an execution, which operations are followed by terminal.
+// the stream is not modified, so it can be executed by many goroutines at the same time
+func (s *stream[E]) getStageMachine(terminal ...stage) *stageMachine {
 	ret := &stageMachine{
-		s: s,
+		src:       s.src(),
+		workers:   int(s.para),
+		unordered: s.unordered,
+	}
+	if s.ctx != nil {
+		ret.ctx, ret.done = s.ctx, s.ctx.Done()
 	}
 
-	stages := make([][]stage, 0)
-	for _, sg := range s.opts {
-		if sLen := len(stages); sLen > 0 {
-			if stages[sLen-1][0].stageFlag == sg.stageFlag && sg.stageFlag == stageStateless {
-				// only stateless actions can be group
-				stages[sLen-1] = append(stages[sLen-1], sg)
-			} else {
-				stages = append(stages, make([]stage, 1))
-				stages[sLen][0] = sg
-			}
-		} else {
-			stages = append(stages, make([]stage, 1))
-			stages[0][0] = sg
+	// the stages are copied, they are modified for this execution
+	opts := make([]stage, 0, len(s.opts)+len(terminal))
+	opts = append(append(opts, s.opts...), terminal...)
+	if !s.unoptimized {
+		opts = optimize(opts)
+	}
+	for i := len(opts) - 1; i >= 0; i-- {
+		if opts[i].kind == kindLimit && opts[i].param.(int) <= 0 {
+			// no element passes the limit, so the source and the stages before it are not executed
+			ret.src, opts = sliceIterator(nil), opts[i:]
+			break
 		}
-		if sg.prepare != nil {
-			sg.prepare()
+	}
+	for i, sg := range opts {
+		if sg.init != nil {
+			// the stage has its own state in this execution
+			fresh := sg.init()
+			opts[i].action, opts[i].flush = fresh.action, fresh.flush
 		}
 	}
-	ret.stages = stages
+	ret.stages = group(opts, ret.workers > 0)
+	if s.hook != nil {
+		ret.profile = instrument(ret)
+	}
 	return ret
 }
 
-func (s *stream) terminate(sg stage) {
-	opts := s.opts
-	// each add stage was a make(), so the opts.cap always equal to opts.len
-	// that append will alloc a new slice
-	s.opts = append(s.opts, sg)
-	machine := s.getStageMachine()
-	machine.run()
-	s.opts = opts // make last effect (terminate op) unavailable
+// iterator executes the stream and returns the products as iterator, which are pulled lazily as far as possible.
+// it panics with the *StageError if the execution fails. if the stream is instrumented,
+// the report is sent when the iterator has no more element or fails
+func (s *stream[E]) iterator() iterator {
+	m := s.getStageMachine()
+	it := m.guard(m.run().iterator())
+	if m.profile == nil {
+		return it
+	}
+	reported := false
+	report := func() {
+		if !reported {
+			reported = true
+			s.hook.Executed(m.profile.report())
+		}
+	}
+	return func() (T, bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				report()
+				panic(r)
+			}
+		}()
+		e, ok := it()
+		if !ok {
+			report()
+		}
+		return e, ok
+	}
+}
 
-	// s.opts = s.opts[:len(s.opts)-1] // make last effect (terminate op) unavailable
+// terminate executes the stream with the terminal stage, it panics with the *StageError if any stage fails
+func (s *stream[E]) terminate(sg stage) {
+	if err := s.execute(sg); err != nil {
+		panic(err)
+	}
 }
 
-func (s *stream) addStage(action func(ele T, i int) (R, bool), flag int, prepare func()) *stream {
-	ret := &stream{
-		data: s.data,
-		opts: make([]stage, len(s.opts)+1),
-		para: s.para,
+// execute executes the stream with the terminal stage, and returns the first error of stages
+func (s *stream[E]) execute(sg stage) error {
+	machine := s.getStageMachine(sg)
+	machine.run()
+	if machine.profile != nil {
+		s.hook.Executed(machine.profile.report())
 	}
-	copy(ret.opts, s.opts)
-	ret.opts[len(s.opts)] = stage{
-		prepare:   prepare,
+	return machine.err
+}
+
+func (s *stream[E]) addStage(name string, action func(ele T, i int) (R, int), flag int) *stream[E] {
+	return s.appendStage(stage{
+		name:      name,
 		action:    action,
 		stageFlag: flag,
+	})
+}
+
+func (s *stream[E]) appendStage(sg stage) *stream[E] {
+	ret := &stream[E]{
+		src:       s.src,
+		opts:      make([]stage, len(s.opts)+1),
+		para:      s.para,
+		unordered: s.unordered,
+		ctx:       s.ctx,
+		hook:      s.hook,
+
+		unoptimized: s.unoptimized,
 	}
+	copy(ret.opts, s.opts)
+	ret.opts[len(s.opts)] = sg
 	return ret
 }
 
-// Concat concat with stream
-func (s *stream) Concat(other Stream) Stream {
-	return &stream{
-		data: append(s.data, other.(*stream).data...), // concat the source, but it operation will not effects outside
-		opts: append(s.opts, other.(*stream).opts...), // then concat the opts
-		para: s.para + other.(*stream).para,
+// Interleave takes elements from the stream and other in turn, the rest elements of the longer one are appended
+func (s *stream[E]) Interleave(other Stream[E]) Stream[E] {
+	return s.appendStage(interleaveStage(other.(*stream[E]).iterator))
+}
+
+// Zip combines each element with the element of other at the same position by f,
+// the elements of the longer one are ignored
+func (s *stream[E]) Zip(other Stream[E], f BinaryOperator[E]) Stream[E] {
+	return Zip[E, E, E](s, other, BiFunction[E, E, E](f))
+}
+
+// ZipAll combines each element with the element of other at the same position by f,
+// the shorter one is padded by pad until both have no more element
+func (s *stream[E]) ZipAll(other Stream[E], pad E, f BinaryOperator[E]) Stream[E] {
+	return ZipAll[E, E, E](s, other, pad, pad, BiFunction[E, E, E](f))
+}
+
+// Chunk groups elements in slices of size, the last one may be smaller. the elements of result are []E
+func (s *stream[E]) Chunk(size int) Stream[T] {
+	return s.Windowed(size, size, true)
+}
+
+// Windowed produces sliding windows of size, which start every step elements. the elements of result are []E.
+// if partial is true, the windows at the end that are smaller than size are produced too
+func (s *stream[E]) Windowed(size int, step int, partial bool) Stream[T] {
+	// the method can't call the function Windowed, which instantiates stream[[]E] recursively
+	return retype[T](s.appendStage(windowStage[E](size, step, partial)))
+}
+
+// Concat concat with stream, the elements of other follow the elements of the stream.
+// each stream executes its own operations, and other is executed only when the elements of the stream run out
+func (s *stream[E]) Concat(other Stream[E]) Stream[E] {
+	return ConcatAll[E](s, other)
+}
+
+// Parallel executes the stream with workers goroutines, workers is the number of CPUs if it is not positive.
+// the functions passed to stages must be safe for concurrent use. the elements are pulled in batches,
+// so the short-circuiting operations stop the infinite sources
+func (s *stream[E]) Parallel(workers int) Stream[E] {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
 	}
+	ret := *s
+	ret.para = uint32(workers)
+	return &ret
+}
+
+// Sequential executes the stream in the caller goroutine
+func (s *stream[E]) Sequential() Stream[E] {
+	ret := *s
+	ret.para = 0
+	return &ret
+}
+
+// Unordered allows parallel execution produce elements out of encounter order
+func (s *stream[E]) Unordered() Stream[E] {
+	ret := *s
+	ret.unordered = true
+	return &ret
+}
+
+// WithContext executes the stream with ctx. the execution stops as soon as ctx is done,
+// then the terminal operations return ctx.Err() if they can, otherwise they panic with it.
+// the cancellation is checked between elements and between stages, and by the workers when it executes parallel
+func (s *stream[E]) WithContext(ctx context.Context) Stream[E] {
+	ret := *s
+	ret.ctx = ctx
+	return &ret
+}
+
+// Optimize enables or disables the optimizer, which is enabled by default.
+// the optimizer rewrites the operations to the equivalent ones which execute faster, like Sort followed by Limit
+// is executed by a bounded heap. disable it to debug the operations as they are written
+func (s *stream[E]) Optimize(enabled bool) Stream[E] {
+	ret := *s
+	ret.unoptimized = !enabled
+	return &ret
 }
 
 // Filter filters out if elements match the condition
-func (s *stream) Filter(f Predicate) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
-			if !f(ele) {
-				ele = nil
+func (s *stream[E]) Filter(f Predicate[E]) Stream[E] {
+	ret := s.addStage("Filter",
+		func(ele T, i int) (R, int) {
+			if !f(as[E](ele)) {
+				return nil, actionDrop
+			}
+			return ele, actionNext
+		}, stageStateless)
+	ret.opts[len(ret.opts)-1].kind = kindFilter
+	return ret
+}
+
+// FilterE filters out if elements match the condition, the stream stops at the first error of f
+func (s *stream[E]) FilterE(f PredicateE[E]) Stream[E] {
+	return s.addStage("FilterE",
+		func(ele T, i int) (R, int) {
+			ok, err := f(as[E](ele))
+			if err != nil {
+				return err, actionFail
+			}
+			if !ok {
+				return nil, actionDrop
 			}
-			return ele, false
-		}, stageStateless, nil)
+			return ele, actionNext
+		}, stageStateless)
+}
+
+// FlatMap replaces each element with the elements of the stream produced by f,
+// the produced stream is executed only when downstream pulls its elements
+func (s *stream[E]) FlatMap(f Function[E, Stream[E]]) Stream[E] {
+	return s.addStage("FlatMap",
+		func(ele T, i int) (R, int) {
+			return f(as[E](ele)).(*stream[E]).iterator(), actionExpand
+		}, stageStateless)
+}
+
+// FlatMapSlice replaces each element with the elements of the slice produced by f
+func (s *stream[E]) FlatMapSlice(f Function[E, []E]) Stream[E] {
+	return s.addStage("FlatMapSlice",
+		func(ele T, i int) (R, int) {
+			return typedSliceIterator(f(as[E](ele))), actionExpand
+		}, stageStateless)
+}
+
+// Flatten replaces each element which is a slice or an array with its elements, other elements are kept
+func (s *stream[E]) Flatten() Stream[T] {
+	return retype[T](s.addStage("Flatten",
+		func(ele T, i int) (R, int) {
+			if prod, ok := ele.([]T); ok {
+				return sliceIterator(prod), actionExpand
+			}
+			if ele == nil {
+				return ele, actionNext
+			}
+			v := reflect.ValueOf(ele)
+			if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+				return ele, actionNext
+			}
+			return reflectIterator(v), actionExpand
+		}, stageStateless))
 }
 
 // Limit limits elements
-func (s *stream) Limit(limit int) Stream {
-	count := 0
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
-			if count >= limit {
-				return nil, true
-			}
-			count++
-			return ele, false
-		}, stageStateless, func() {
-			count = 0
-		})
+func (s *stream[E]) Limit(limit int) Stream[E] {
+	return s.appendStage(limitStage(limit))
 }
 
-// Map maps elements with function
-func (s *stream) Map(f Function) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
-			return f(ele), false
-		}, stageStateless, nil)
+// Map maps elements with function, use the function Map if the result is another type
+func (s *stream[E]) Map(f UnaryOperator[E]) Stream[E] {
+	return s.addStage("Map",
+		func(ele T, i int) (R, int) {
+			return f(as[E](ele)), actionNext
+		}, stageStateless)
 }
 
-// Skip skips elements
-func (s *stream) Skip(num int) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
-			if i < num {
-				return nil, false
+// MapE maps elements with function, the stream stops at the first error of f.
+// use the function MapE if the result is another type
+func (s *stream[E]) MapE(f FunctionE[E, E]) Stream[E] {
+	return s.addStage("MapE",
+		func(ele T, i int) (R, int) {
+			e, err := f(as[E](ele))
+			if err != nil {
+				return err, actionFail
 			}
-			return ele, false
-		}, stageStateless, nil)
+			return e, actionNext
+		}, stageStateless)
+}
+
+// Skip skips elements
+func (s *stream[E]) Skip(num int) Stream[E] {
+	return s.appendStage(skipStage(num))
 }
 
 // Slice return the stream with[start, start+count)
-func (s *stream) Slice(start, count int) Stream {
+func (s *stream[E]) Slice(start, count int) Stream[E] {
 	return s.Skip(start).Limit(count)
 }
 
-// Fill fill the stream with T
-func (s *stream) Fill(e T) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
-			return e, false
-		}, stageStateless, nil)
+// Fill fill the stream with E
+func (s *stream[E]) Fill(e E) Stream[E] {
+	return s.addStage("Fill",
+		func(ele T, i int) (R, int) {
+			return e, actionNext
+		}, stageStateless)
 }
 
 // Pop pop the last element
-func (s *stream) Pop() Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
+func (s *stream[E]) Pop() Stream[E] {
+	return s.addStage("Pop",
+		func(ele T, i int) (R, int) {
 			prod := ele.([]T)
-			return prod[:i-1], false
-		}, stageStateful, nil)
+			return prod[:i-1], actionNext
+		}, stageStateful)
 }
 
 // Push insert the element at last
-func (s *stream) Push(e T) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
+func (s *stream[E]) Push(e E) Stream[E] {
+	return s.addStage("Push",
+		func(ele T, i int) (R, int) {
 			prod := ele.([]T)
-			return append(prod, e), false
-		}, stageStateful, nil)
+			return append(prod, e), actionNext
+		}, stageStateful)
 }
 
 // Reverse reverse the stream
-func (s *stream) Reverse() Stream {
-	return s.addStage(
-		func(ele T, pLen int) (R, bool) {
+func (s *stream[E]) Reverse() Stream[E] {
+	ret := s.addStage("Reverse",
+		func(ele T, pLen int) (R, int) {
 			prod := ele.([]T)
 			last := pLen - 1
 			for i := 0; i < pLen/2; i++ {
 				prod[i], prod[last-i] = prod[last-i], prod[i]
 			}
-			return prod, false
-		}, stageStateful, nil)
+			return prod, actionNext
+		}, stageStateful)
+	ret.opts[len(ret.opts)-1].kind = kindReverse
+	return ret
 }
 
 // Shift remove the first element
-func (s *stream) Shift() Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
+func (s *stream[E]) Shift() Stream[E] {
+	return s.addStage("Shift",
+		func(ele T, i int) (R, int) {
 			prod := ele.([]T)
-			return prod[1:], false
-		}, stageStateful, nil)
+			return prod[1:], actionNext
+		}, stageStateful)
 }
 
 // Unique de-duplicates elements
-func (s *stream) Unique(f IntFunction) Stream {
-	return s.addStage(
-		func(ele T, sLen int) (R, bool) {
+func (s *stream[E]) Unique(f IntFunction[E]) Stream[E] {
+	return s.addStage("Unique",
+		func(ele T, sLen int) (R, int) {
 			prod := ele.([]T)
 			set := make(map[int]bool)
 			i := 0
 			for _, e := range prod {
-				h := f(e)
+				h := f(as[E](e))
 				if _, ok := set[h]; !ok {
 					set[h] = true
 					prod[i] = e
 					i++
 				}
 			}
-			return prod[:i], false
-		}, stageStateful, nil)
+			return prod[:i], actionNext
+		}, stageStateful)
 }
 
 // Unshift insert the element at front
-func (s *stream) Unshift(e T) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
+func (s *stream[E]) Unshift(e E) Stream[E] {
+	return s.addStage("Unshift",
+		func(ele T, i int) (R, int) {
 			prod := ele.([]T)
-			return append([]T{e}, prod...), false
-		}, stageStateful, nil)
+			return append([]T{e}, prod...), actionNext
+		}, stageStateful)
 }
 
 // Sort sorts elements
-func (s *stream) Sort(f Comparator) Stream {
-	return s.addStage(
-		func(ele T, i int) (R, bool) {
-			sort.Sort(&sortable{
-				List: ele.([]T),
-				Cmp:  f,
-			})
-			return ele, false
-		}, stageStateful, nil)
+func (s *stream[E]) Sort(f Comparator[E]) Stream[E] {
+	return s.sort(f, false)
+}
+
+// SortStable sorts elements, the equal elements keep their encounter order
+func (s *stream[E]) SortStable(f Comparator[E]) Stream[E] {
+	return s.sort(f, true)
+}
+
+func (s *stream[E]) sort(f Comparator[E], stable bool) *stream[E] {
+	cmp := func(left T, right T) int {
+		return f(as[E](left), as[E](right))
+	}
+	name := "Sort"
+	if stable {
+		name = "SortStable"
+	}
+	ret := s.addStage(name,
+		func(ele T, i int) (R, int) {
+			sortSlice(ele.([]T), cmp, stable)
+			return ele, actionNext
+		}, stageStateful)
+	ret.opts[len(ret.opts)-1].parallel = func(chunks [][]T, workers int, stopped func() bool) []T {
+		return parallelSort(chunks, cmp, workers, stable, stopped)
+	}
+	ret.opts[len(ret.opts)-1].kind = kindSort
+	ret.opts[len(ret.opts)-1].param = cmp
+	return ret
 }
 
-// AllMatch test if all elements match the condition
-func (s *stream) AllMatch(f Predicate) (ret bool) {
+// AllMatch test if all elements match the condition, it is true if no element
+func (s *stream[E]) AllMatch(f Predicate[E]) (ret bool) {
+	ret = true
 	s.terminate(stage{
-		action: func(ele T, i int) (R, bool) {
-			ret = f(ele)
-			return nil, !ret
+		name: "AllMatch",
+		action: func(ele T, i int) (R, int) {
+			if ret = f(as[E](ele)); !ret {
+				return nil, actionStop
+			}
+			return nil, actionNext
 		},
 		stageFlag: stageShortcut,
 	})
@@ -249,11 +526,31 @@ func (s *stream) AllMatch(f Predicate) (ret bool) {
 }
 
 // AnyMatch test if any element matches the condition
-func (s *stream) AnyMatch(f Predicate) (ret bool) {
+func (s *stream[E]) AnyMatch(f Predicate[E]) (ret bool) {
+	s.terminate(stage{
+		name: "AnyMatch",
+		action: func(ele T, i int) (R, int) {
+			if ret = f(as[E](ele)); ret {
+				return nil, actionStop
+			}
+			return nil, actionNext
+		},
+		stageFlag: stageShortcut,
+	})
+	return
+}
+
+// NoneMatch test if no element matches the condition
+func (s *stream[E]) NoneMatch(f Predicate[E]) (ret bool) {
+	ret = true
 	s.terminate(stage{
-		action: func(ele T, i int) (R, bool) {
-			ret = f(ele)
-			return nil, ret
+		name: "NoneMatch",
+		action: func(ele T, i int) (R, int) {
+			if f(as[E](ele)) {
+				ret = false
+				return nil, actionStop
+			}
+			return nil, actionNext
 		},
 		stageFlag: stageShortcut,
 	})
@@ -261,115 +558,539 @@ func (s *stream) AnyMatch(f Predicate) (ret bool) {
 }
 
 // Count return the count of stream
-func (s *stream) Count() (ret int) {
+func (s *stream[E]) Count() (ret int) {
 	s.terminate(stage{
-		action: func(ele T, i int) (R, bool) {
+		name: "Count",
+		action: func(ele T, i int) (R, int) {
 			ret = i
-			return nil, true
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			// the chunks are counted without joining them
+			for _, c := range chunks {
+				ret += len(c)
+			}
+			return nil
 		},
 		stageFlag: stageNonShortcut,
 	})
 	return
 }
 
-// FindFirst return the first element that matches the condition
-func (s *stream) FindFirst(f Predicate) (ret T) {
+// FindFirst return the first element that matches the condition, empty if no element matches
+func (s *stream[E]) FindFirst(f Predicate[E]) (ret Optional[E]) {
+	s.terminate(stage{
+		name: "FindFirst",
+		action: func(ele T, i int) (R, int) {
+			if e := as[E](ele); f(e) {
+				ret = OptionalOf(e)
+				return nil, actionStop
+			}
+			return nil, actionNext
+		},
+		stageFlag: stageShortcut,
+	})
+	return
+}
+
+// First return the first element, empty if no element
+func (s *stream[E]) First() (ret Optional[E]) {
+	s.terminate(stage{
+		name: "First",
+		action: func(ele T, i int) (R, int) {
+			ret = OptionalOf(as[E](ele))
+			return nil, actionStop
+		},
+		stageFlag: stageShortcut,
+	})
+	return
+}
+
+// FindAny return any element that matches the condition, empty if no element matches.
+// it is the first one if the stream executes sequential, otherwise workers search the chunks of each batch
+// at the same time, and the first found is returned
+func (s *stream[E]) FindAny(f Predicate[E]) Optional[E] {
+	var ret E
+	found := false
 	s.terminate(stage{
-		action: func(ele T, i int) (R, bool) {
-			if f(ele) {
-				ret = ele
-				return nil, true
+		name: "FindAny",
+		action: func(ele T, i int) (R, int) {
+			if e := as[E](ele); f(e) {
+				ret, found = e, true
+				return nil, actionStop
 			}
-			return nil, false
+			return nil, actionNext
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			done := int32(0)
+			mu := sync.Mutex{}
+			runWorkers(workers, len(chunks), func(c int) {
+				for _, ele := range chunks[c] {
+					if atomic.LoadInt32(&done) == 1 || stopped() {
+						return
+					}
+					if e := as[E](ele); f(e) {
+						mu.Lock()
+						if !found {
+							ret, found = e, true
+							atomic.StoreInt32(&done, 1)
+						}
+						mu.Unlock()
+						return
+					}
+				}
+			})
+			if found {
+				// the rest batches are not pulled
+				return []T{ret}
+			}
+			return nil
 		},
 		stageFlag: stageShortcut,
 	})
+	return optional(ret, found)
+}
+
+// FindLast return the last element that matches the condition, empty if no element matches
+func (s *stream[E]) FindLast(f Predicate[E]) (ret Optional[E]) {
+	s.terminate(stage{
+		name: "FindLast",
+		action: func(ele T, i int) (R, int) {
+			prod := ele.([]T)
+			for i := len(prod) - 1; i >= 0; i-- {
+				if e := as[E](prod[i]); f(e) {
+					ret = OptionalOf(e)
+					break
+				}
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return
+}
+
+// Last return the last element, empty if no element
+func (s *stream[E]) Last() (ret Optional[E]) {
+	s.terminate(stage{
+		name: "Last",
+		action: func(ele T, i int) (R, int) {
+			if prod := ele.([]T); len(prod) > 0 {
+				ret = OptionalOf(as[E](prod[len(prod)-1]))
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	})
 	return
 }
 
+// Max return the max element by comparator, the first one if there are many, empty if no element
+func (s *stream[E]) Max(f Comparator[E]) Optional[E] {
+	return best(s, "Max", func(e E) E {
+		return e
+	}, func(k E, than E) bool {
+		return f(k, than) > 0
+	})
+}
+
+// Min return the min element by comparator, the first one if there are many, empty if no element
+func (s *stream[E]) Min(f Comparator[E]) Optional[E] {
+	return best(s, "Min", func(e E) E {
+		return e
+	}, func(k E, than E) bool {
+		return f(k, than) < 0
+	})
+}
+
+// best return the first element whose key is better than the keys of others, empty if no element.
+// the key of each element is computed only once
+func best[E, K any](s *stream[E], name string, key func(E) K, better func(k K, than K) bool) Optional[E] {
+	type candidate struct {
+		e     E
+		k     K
+		found bool
+	}
+	scan := func(prod []T, stopped func() bool) (c candidate) {
+		for _, ele := range prod {
+			if stopped() {
+				return
+			}
+			e := as[E](ele)
+			if k := key(e); !c.found || better(k, c.k) {
+				c = candidate{e: e, k: k, found: true}
+			}
+		}
+		return
+	}
+
+	var ret candidate
+	s.terminate(stage{
+		name: name,
+		action: func(ele T, i int) (R, int) {
+			ret = scan(ele.([]T), never)
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			parts := make([]candidate, len(chunks))
+			runWorkers(workers, len(chunks), func(i int) {
+				parts[i] = scan(chunks[i], stopped)
+			})
+			for _, c := range parts {
+				if c.found && (!ret.found || better(c.k, ret.k)) {
+					ret = c
+				}
+			}
+			return nil
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return optional(ret.e, ret.found)
+}
+
 // ForEach traversal the stream
-func (s *stream) ForEach(f Consumer) {
+func (s *stream[E]) ForEach(f Consumer[E]) {
 	s.terminate(stage{
-		action: func(ele T, i int) (R, bool) {
+		name: "ForEach",
+		action: func(ele T, i int) (R, int) {
 			for _, e := range ele.([]T) {
-				f(e)
+				f(as[E](e))
 			}
-			return nil, true
+			return nil, actionStop
 		},
 		stageFlag: stageNonShortcut,
 	})
 }
 
+// ForEachE traversal the stream, it stops at the first error of f and returns it.
+// the elements are consumed one by one in encounter order, even if the stream executes parallel
+func (s *stream[E]) ForEachE(f ConsumerE[E]) error {
+	return s.execute(stage{
+		name: "ForEachE",
+		action: func(ele T, i int) (R, int) {
+			if err := f(as[E](ele)); err != nil {
+				return err, actionFail
+			}
+			return nil, actionNext
+		},
+		stageFlag: stageShortcut,
+	})
+}
+
 // Join join all element with splitter
-func (s *stream) Join(split string) string {
-	return s.Reduce(func(acc R, e T, i int, sLen int) R {
-		sb := acc.(*strings.Builder)
-		sb.WriteString(fmt.Sprintf("%v", e))
-		if i < sLen-1 {
-			sb.WriteString(split)
-		}
-		return sb
-	}, &strings.Builder{}).(*strings.Builder).String()
+func (s *stream[E]) Join(split string) string {
+	sb := &strings.Builder{}
+	s.terminate(stage{
+		name: "Join",
+		action: func(ele T, sLen int) (R, int) {
+			for i, e := range ele.([]T) {
+				sb.WriteString(fmt.Sprintf("%v", e))
+				if i < sLen-1 {
+					sb.WriteString(split)
+				}
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return sb.String()
 }
 
-// Reduce return nil if no element. calculate result by (R, T) -> R from init element, panic if reduction is nil
-func (s *stream) Reduce(accumulator func(R, T, int, int) R, initValue R) (ret R) {
+// Reduce return initValue if no element. calculate result by (E, E) -> E from init element.
+// when the stream executes parallel, each chunk is reduced from initValue and the results of chunks are reduced
+// by accumulator again, so the accumulator must be associative and initValue must be its identity
+// use ReduceWithCombiner to reduce to another type, which is safe to execute parallel
+func (s *stream[E]) Reduce(accumulator func(E, E, int, int) E, initValue E) (ret E) {
 	ret = initValue
 	s.terminate(stage{
-		action: func(ele T, sLen int) (R, bool) {
+		name: "Reduce",
+		action: func(ele T, sLen int) (R, int) {
 			for i, e := range ele.([]T) {
-				ret = accumulator(ret, e, i, sLen)
+				ret = accumulator(ret, as[E](e), i, sLen)
 			}
-			return nil, true
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			parts := make([]E, len(chunks))
+			runWorkers(workers, len(chunks), func(c int) {
+				part := initValue
+				for i, e := range chunks[c] {
+					if stopped() {
+						return
+					}
+					part = accumulator(part, as[E](e), i, len(chunks[c]))
+				}
+				parts[c] = part
+			})
+			for i, part := range parts {
+				ret = accumulator(ret, part, i, len(parts))
+			}
+			return nil
 		},
 		stageFlag: stageNonShortcut,
 	})
 	return
 }
 
-// ToSlice reduce the stream to slice
-func (s *stream) ToSlice() (ret []T) {
+// ReduceE like Reduce, but it stops at the first error of accumulator and returns it.
+// the elements are reduced sequentially
+func (s *stream[E]) ReduceE(accumulator func(E, E, int, int) (E, error), initValue E) (ret E, err error) {
+	ret = initValue
+	err = s.execute(stage{
+		name: "ReduceE",
+		action: func(ele T, sLen int) (R, int) {
+			for i, e := range ele.([]T) {
+				acc, err := accumulator(ret, as[E](e), i, sLen)
+				if err != nil {
+					return errorAt(i, err), actionFail
+				}
+				ret = acc
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return
+}
+
+// ReduceOptional reduces elements by (E, E) -> E from the first element, empty if no element.
+// when the stream executes parallel, each non-empty chunk is reduced from its first element and the results of
+// chunks are reduced by f again in encounter order, so f must be associative
+func (s *stream[E]) ReduceOptional(f BinaryOperator[E]) (ret Optional[E]) {
+	reduce := func(acc Optional[E], prod []T, stopped func() bool) Optional[E] {
+		for _, e := range prod {
+			if stopped() {
+				break
+			}
+			if !acc.present {
+				acc = OptionalOf(as[E](e))
+			} else {
+				acc.value = f(acc.value, as[E](e))
+			}
+		}
+		return acc
+	}
 	s.terminate(stage{
-		action: func(ele T, i int) (R, bool) {
-			ret = ele.([]T)
-			return nil, true
+		name: "ReduceOptional",
+		action: func(ele T, i int) (R, int) {
+			ret = reduce(ret, ele.([]T), never)
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			parts := make([]Optional[E], len(chunks))
+			runWorkers(workers, len(chunks), func(c int) {
+				parts[c] = reduce(Optional[E]{}, chunks[c], stopped)
+			})
+			for _, part := range parts {
+				switch {
+				case !part.present:
+				case !ret.present:
+					ret = part
+				default:
+					ret.value = f(ret.value, part.value)
+				}
+			}
+			return nil
 		},
 		stageFlag: stageNonShortcut,
 	})
 	return
 }
 
+// ToSlice reduce the stream to slice
+func (s *stream[E]) ToSlice() (ret []E) {
+	s.terminate(s.toSlice(&ret))
+	return
+}
+
+// ToSliceE like ToSlice, but it returns the error instead of panic if the execution fails
+func (s *stream[E]) ToSliceE() (ret []E, err error) {
+	err = s.execute(s.toSlice(&ret))
+	return
+}
+
+// toSlice returns the terminal stage which reduces the stream to slice ret
+func (s *stream[E]) toSlice(ret *[]E) stage {
+	return stage{
+		name: "ToSlice",
+		action: func(ele T, i int) (R, int) {
+			prod := ele.([]T)
+			if untyped, ok := T(prod).([]E); ok {
+				// the untyped stream, E is T
+				*ret = untyped
+				return nil, actionStop
+			}
+			*ret = make([]E, len(prod))
+			for i, e := range prod {
+				(*ret)[i] = as[E](e)
+			}
+			return nil, actionStop
+		},
+		stageFlag: stageNonShortcut,
+	}
+}
+
 // Of creates a Stream from slice
-func Of(elements ...T) Stream {
-	return &stream{
-		data: elements,
+func Of(elements ...T) Stream[T] {
+	return &stream[T]{
+		src: func() iterator {
+			return sliceIterator(elements)
+		},
 		opts: make([]stage, 0),
 		para: 0,
 	}
 }
 
-// OfSlice construct a stream form slice
-func OfSlice(s T) Stream {
+// OfSlice construct a stream form slice, array or string, the elements are read from it lazily.
+// the elements of string are bytes. the common slice types and string are read without reflection
+func OfSlice(s T) Stream[T] {
 	// if reflect.TypeOf(s).Kind() != reflect.Slice {
 	// 	panic("arg is not slice")
 	// }
-	v := reflect.ValueOf(s)
-	it := make([]T, v.Len())
-	for i := range it {
-		it[i] = v.Index(i).Interface()
+	var src func() iterator
+	switch data := s.(type) {
+	case []T:
+		src = func() iterator {
+			return sliceIterator(data)
+		}
+	case []int:
+		src = func() iterator {
+			return typedSliceIterator(data)
+		}
+	case []int64:
+		src = func() iterator {
+			return typedSliceIterator(data)
+		}
+	case []float64:
+		src = func() iterator {
+			return typedSliceIterator(data)
+		}
+	case []string:
+		src = func() iterator {
+			return typedSliceIterator(data)
+		}
+	case []byte:
+		src = func() iterator {
+			return typedSliceIterator(data)
+		}
+	case string:
+		src = func() iterator {
+			return byteIterator(data)
+		}
+	default:
+		v := reflect.ValueOf(s)
+		src = func() iterator {
+			return reflectIterator(v)
+		}
+	}
+	return &stream[T]{
+		src:  src,
+		opts: make([]stage, 0),
+		para: 0,
+	}
+}
+
+// OfInts creates a Stream from slice of int
+func OfInts(s []int) Stream[int] {
+	return FromSlice(s)
+}
+
+// OfStrings creates a Stream from slice of string
+func OfStrings(s []string) Stream[string] {
+	return FromSlice(s)
+}
+
+// OfBytes creates a Stream of the bytes of string, the string is not copied
+func OfBytes(s string) Stream[byte] {
+	return &stream[byte]{
+		src: func() iterator {
+			return byteIterator(s)
+		},
+		opts: make([]stage, 0),
+		para: 0,
 	}
-	return &stream{
-		data: it,
+}
+
+// OfRunes creates a Stream of the Unicode code points of string,
+// each invalid UTF-8 byte is utf8.RuneError as range loop does
+func OfRunes(s string) Stream[rune] {
+	return &stream[rune]{
+		src: func() iterator {
+			return runeIterator(s)
+		},
 		opts: make([]stage, 0),
 		para: 0,
 	}
 }
 
 // Repeat is constructor of repeat element
-func Repeat(ele T, times int) Stream {
-	s := make([]T, times)
-	for i := range s {
-		s[i] = ele
+func Repeat(ele T, times int) Stream[T] {
+	return &stream[T]{
+		src: func() iterator {
+			i := 0
+			return func() (T, bool) {
+				if i >= times {
+					return nil, false
+				}
+				i++
+				return ele, true
+			}
+		},
+		opts: make([]stage, 0),
+		para: 0,
+	}
+}
+
+// Generate is constructor of infinite stream, each element is supplied by f when it is pulled.
+// use short-circuiting operations like Limit, FindFirst or AnyMatch to stop it
+func Generate[E any](f Supplier[E]) Stream[E] {
+	return &stream[E]{
+		src: func() iterator {
+			return func() (T, bool) {
+				return f(), true
+			}
+		},
+		opts: make([]stage, 0),
+		para: 0,
+	}
+}
+
+// Iterate is constructor of infinite stream seed, f(seed), f(f(seed)) ...
+// f is applied when the next element is pulled, so it is never applied to the last element pulled
+func Iterate[E any](seed E, f UnaryOperator[E]) Stream[E] {
+	return &stream[E]{
+		src: func() iterator {
+			var prev E
+			started := false
+			return func() (T, bool) {
+				if !started {
+					prev, started = seed, true
+				} else {
+					prev = f(prev)
+				}
+				return prev, true
+			}
+		},
+		opts: make([]stage, 0),
+		para: 0,
+	}
+}
+
+// Cycle is constructor of infinite stream, which repeats the elements endlessly. it is empty if no element
+func Cycle[E any](elements ...E) Stream[E] {
+	return &stream[E]{
+		src: func() iterator {
+			i := 0
+			return func() (T, bool) {
+				if len(elements) == 0 {
+					return nil, false
+				}
+				e := elements[i%len(elements)]
+				i++
+				return e, true
+			}
+		},
+		opts: make([]stage, 0),
+		para: 0,
 	}
-	return Of(s...)
 }
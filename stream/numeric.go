@@ -0,0 +1,340 @@
+package stream
+
+// NumberStream is a stream of numbers, which has the numeric terminal operations.
+// use Boxed to get the Stream of the numbers for other operations
+type NumberStream[N Number] interface {
+	// Parallel executes the stream with workers goroutines, workers is the number of CPUs if it is not positive
+	Parallel(workers int) NumberStream[N]
+	// Sequential executes the stream in the caller goroutine
+	Sequential() NumberStream[N]
+
+	// Filter filters out if numbers match the condition
+	Filter(Predicate[N]) NumberStream[N]
+	// Map maps numbers with function
+	Map(UnaryOperator[N]) NumberStream[N]
+	// Limit limits numbers
+	Limit(int) NumberStream[N]
+	// Skip skips numbers
+	Skip(int) NumberStream[N]
+	// Sorted sorts numbers in ascending order, NaN is the greatest
+	Sorted() NumberStream[N]
+	// Boxed returns the Stream of numbers
+	Boxed() Stream[N]
+
+	// Count return the count of numbers
+	Count() int
+	// Sum return the sum of numbers, 0 if no number
+	Sum() N
+	// Average return the arithmetic mean of numbers, empty if no number
+	Average() Optional[float64]
+	// Max return the max number, empty if no number
+	Max() Optional[N]
+	// Min return the min number, empty if no number
+	Min() Optional[N]
+	// SummaryStatistics return the count, sum, min, max, mean and variance of numbers
+	SummaryStatistics() SummaryStatistics[N]
+	// ToSlice reduce the stream to slice
+	ToSlice() []N
+}
+
+type (
+	// IntStream is a NumberStream of int64
+	IntStream = NumberStream[int64]
+	// FloatStream is a NumberStream of float64
+	FloatStream = NumberStream[float64]
+)
+
+// SummaryStatistics is the statistics of numbers
+type SummaryStatistics[N Number] struct {
+	Count int     // Count is the count of numbers
+	Sum   N       // Sum is the sum of numbers
+	Min   N       // Min is the min number, 0 if no number
+	Max   N       // Max is the max number, 0 if no number
+	Mean  float64 // Mean is the arithmetic mean of numbers, 0 if no number
+	// Variance is the population variance of numbers, 0 if no number
+	Variance float64
+
+	m2 float64 // m2 is the sum of squares of differences from the mean
+}
+
+// add adds e to the statistics by Welford's algorithm, which is numerically stable
+func (st *SummaryStatistics[N]) add(e N) {
+	st.Count++
+	st.Sum += e
+	if st.Count == 1 || compare(e, st.Min) < 0 {
+		st.Min = e
+	}
+	if st.Count == 1 || compare(e, st.Max) > 0 {
+		st.Max = e
+	}
+	delta := float64(e) - st.Mean
+	st.Mean += delta / float64(st.Count)
+	st.m2 += delta * (float64(e) - st.Mean)
+}
+
+// merge merges the statistics of other numbers into st
+func (st *SummaryStatistics[N]) merge(other SummaryStatistics[N]) {
+	switch {
+	case other.Count == 0:
+		return
+	case st.Count == 0:
+		*st = other
+		return
+	}
+	count := st.Count + other.Count
+	delta := other.Mean - st.Mean
+	st.m2 += other.m2 + delta*delta*float64(st.Count)*float64(other.Count)/float64(count)
+	st.Mean += delta * float64(other.Count) / float64(count)
+	st.Count = count
+	st.Sum += other.Sum
+	if compare(other.Min, st.Min) < 0 {
+		st.Min = other.Min
+	}
+	if compare(other.Max, st.Max) > 0 {
+		st.Max = other.Max
+	}
+}
+
+type numberStream[N Number] struct {
+	s *stream[N]
+}
+
+// Numbers converts a Stream of numbers to NumberStream
+func Numbers[N Number](s Stream[N]) NumberStream[N] {
+	return numberStream[N]{s: s.(*stream[N])}
+}
+
+// MapToInt maps elements of s to int64 with function
+func MapToInt[T any](s Stream[T], f Function[T, int64]) IntStream {
+	return Numbers(Map(s, f))
+}
+
+// MapToFloat maps elements of s to float64 with function
+func MapToFloat[T any](s Stream[T], f Function[T, float64]) FloatStream {
+	return Numbers(Map(s, f))
+}
+
+// Range is constructor of numbers from start to end exclusive by step, which is negative if the numbers decrease.
+// it panics if step is 0
+func Range[N Number](start N, end N, step N) NumberStream[N] {
+	return numberRange(start, end, step, false)
+}
+
+// RangeClosed is constructor of numbers from start to end inclusive by step, which is negative if the numbers decrease.
+// it panics if step is 0
+func RangeClosed[N Number](start N, end N, step N) NumberStream[N] {
+	return numberRange(start, end, step, true)
+}
+
+func numberRange[N Number](start N, end N, step N, closed bool) NumberStream[N] {
+	var zero N
+	if step == zero {
+		panic("step of range must not be 0")
+	}
+	// beyond reports whether e is out of range
+	beyond := func(e N) bool {
+		if step > zero {
+			return e > end || !closed && e == end
+		}
+		return e < end || !closed && e == end
+	}
+	return numberStream[N]{s: &stream[N]{
+		src: func() iterator {
+			next, done := start, false
+			return func() (T, bool) {
+				if done || beyond(next) {
+					return nil, false
+				}
+				e := next
+				// stop if the next number overflows or can't be represented
+				if next += step; (step > zero) != (next > e) {
+					done = true
+				}
+				return e, true
+			}
+		},
+		opts: make([]stage, 0),
+		para: 0,
+	}}
+}
+
+// wrap returns the NumberStream of s
+func (n numberStream[N]) wrap(s Stream[N]) NumberStream[N] {
+	return numberStream[N]{s: s.(*stream[N])}
+}
+
+// Parallel executes the stream with workers goroutines, workers is the number of CPUs if it is not positive
+func (n numberStream[N]) Parallel(workers int) NumberStream[N] {
+	return n.wrap(n.s.Parallel(workers))
+}
+
+// Sequential executes the stream in the caller goroutine
+func (n numberStream[N]) Sequential() NumberStream[N] {
+	return n.wrap(n.s.Sequential())
+}
+
+// Filter filters out if numbers match the condition
+func (n numberStream[N]) Filter(f Predicate[N]) NumberStream[N] {
+	return n.wrap(n.s.Filter(f))
+}
+
+// Map maps numbers with function
+func (n numberStream[N]) Map(f UnaryOperator[N]) NumberStream[N] {
+	return n.wrap(n.s.Map(f))
+}
+
+// Limit limits numbers
+func (n numberStream[N]) Limit(limit int) NumberStream[N] {
+	return n.wrap(n.s.Limit(limit))
+}
+
+// Skip skips numbers
+func (n numberStream[N]) Skip(num int) NumberStream[N] {
+	return n.wrap(n.s.Skip(num))
+}
+
+// Sorted sorts numbers in ascending order, NaN is the greatest
+func (n numberStream[N]) Sorted() NumberStream[N] {
+	return n.wrap(n.s.Sort(compare[N]))
+}
+
+// Boxed returns the Stream of numbers
+func (n numberStream[N]) Boxed() Stream[N] {
+	return n.s
+}
+
+// Count return the count of numbers
+func (n numberStream[N]) Count() int {
+	return n.s.Count()
+}
+
+// Sum return the sum of numbers, 0 if no number
+func (n numberStream[N]) Sum() N {
+	add := func(acc N, e N) N {
+		return acc + e
+	}
+	return reduceNumbers(n.s, "Sum", add, add)
+}
+
+// mean is the sum and count of numbers to average
+type mean struct {
+	sum   float64
+	count int
+}
+
+// Average return the arithmetic mean of numbers, empty if no number
+func (n numberStream[N]) Average() Optional[float64] {
+	ret := reduceNumbers(n.s, "Average", func(acc mean, e N) mean {
+		return mean{sum: acc.sum + float64(e), count: acc.count + 1}
+	}, func(left mean, right mean) mean {
+		return mean{sum: left.sum + right.sum, count: left.count + right.count}
+	})
+	if ret.count == 0 {
+		return EmptyOptional[float64]()
+	}
+	return OptionalOf(ret.sum / float64(ret.count))
+}
+
+// Max return the max number, empty if no number
+func (n numberStream[N]) Max() Optional[N] {
+	return reduceNumbers(n.s, "Max", func(acc Optional[N], e N) Optional[N] {
+		if !acc.present || compare(e, acc.value) > 0 {
+			return OptionalOf(e)
+		}
+		return acc
+	}, func(left Optional[N], right Optional[N]) Optional[N] {
+		if !left.present || right.present && compare(right.value, left.value) > 0 {
+			return right
+		}
+		return left
+	})
+}
+
+// Min return the min number, empty if no number
+func (n numberStream[N]) Min() Optional[N] {
+	return reduceNumbers(n.s, "Min", func(acc Optional[N], e N) Optional[N] {
+		if !acc.present || compare(e, acc.value) < 0 {
+			return OptionalOf(e)
+		}
+		return acc
+	}, func(left Optional[N], right Optional[N]) Optional[N] {
+		if !left.present || right.present && compare(right.value, left.value) < 0 {
+			return right
+		}
+		return left
+	})
+}
+
+// reduceNumbers reduces the numbers of s by accumulator from the zero value of A.
+// when the stream executes parallel, each chunk is reduced by a worker and the results are combined in order
+func reduceNumbers[N Number, A any](s *stream[N], name string, accumulator func(A, N) A, combiner func(A, A) A) (ret A) {
+	reduce := func(prod []T, stopped func() bool) (acc A) {
+		for _, e := range prod {
+			if stopped() {
+				return
+			}
+			acc = accumulator(acc, as[N](e))
+		}
+		return
+	}
+	s.terminate(stage{
+		name: name,
+		action: func(ele T, i int) (R, int) {
+			ret = reduce(ele.([]T), never)
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			parts := make([]A, len(chunks))
+			runWorkers(workers, len(chunks), func(i int) {
+				parts[i] = reduce(chunks[i], stopped)
+			})
+			for _, part := range parts {
+				ret = combiner(ret, part)
+			}
+			return nil
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return
+}
+
+// SummaryStatistics return the count, sum, min, max, mean and variance of numbers.
+// when the stream executes parallel, each chunk is summarized by a worker and the results are merged
+func (n numberStream[N]) SummaryStatistics() (ret SummaryStatistics[N]) {
+	summarize := func(prod []T, stopped func() bool) (st SummaryStatistics[N]) {
+		for _, e := range prod {
+			if stopped() {
+				return
+			}
+			st.add(as[N](e))
+		}
+		return
+	}
+	n.s.terminate(stage{
+		name: "SummaryStatistics",
+		action: func(ele T, i int) (R, int) {
+			ret = summarize(ele.([]T), never)
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			parts := make([]SummaryStatistics[N], len(chunks))
+			runWorkers(workers, len(chunks), func(i int) {
+				parts[i] = summarize(chunks[i], stopped)
+			})
+			for _, part := range parts {
+				ret.merge(part)
+			}
+			return nil
+		},
+		stageFlag: stageNonShortcut,
+	})
+	if ret.Count > 0 {
+		ret.Variance = ret.m2 / float64(ret.Count)
+	}
+	return
+}
+
+// ToSlice reduce the stream to slice
+func (n numberStream[N]) ToSlice() []N {
+	return n.s.ToSlice()
+}
@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		s    IntStream
+		want []int64
+	}{
+		{Range[int64](0, 5, 2), []int64{0, 2, 4}},
+		{Range[int64](0, 4, 2), []int64{0, 2}},
+		{RangeClosed[int64](0, 4, 2), []int64{0, 2, 4}},
+		{Range[int64](5, 0, -2), []int64{5, 3, 1}},
+		{RangeClosed[int64](4, 0, -2), []int64{4, 2, 0}},
+		{Range[int64](0, 0, 1), []int64{}},
+		{RangeClosed[int64](math.MaxInt64-1, math.MaxInt64, 1), []int64{math.MaxInt64 - 1, math.MaxInt64}},
+	}
+	for i, tt := range tests {
+		if got := tt.s.ToSlice(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: ToSlice() = %v, want %v", i, got, tt.want)
+		}
+	}
+	if got := RangeClosed[uint8](250, 255, 5).ToSlice(); !reflect.DeepEqual(got, []uint8{250, 255}) {
+		t.Errorf("RangeClosed() = %v", got)
+	}
+	if got := Range(0, 1, 0.25).ToSlice(); !reflect.DeepEqual(got, []float64{0, 0.25, 0.5, 0.75}) {
+		t.Errorf("Range() = %v", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Range() should panic if step is 0")
+		}
+	}()
+	Range(0, 1, 0)
+}
+
+func TestSummaryStatistics(t *testing.T) {
+	st := RangeClosed(1, 8, 1).SummaryStatistics()
+	want := SummaryStatistics[int]{Count: 8, Sum: 36, Min: 1, Max: 8, Mean: 4.5, Variance: 5.25}
+	st.m2 = 0
+	if st != want {
+		t.Errorf("SummaryStatistics() = %+v, want %+v", st, want)
+	}
+	// parallel gives the same result
+	st = RangeClosed(1, 8, 1).Parallel(3).SummaryStatistics()
+	st.m2 = 0
+	if st != want {
+		t.Errorf("parallel SummaryStatistics() = %+v, want %+v", st, want)
+	}
+
+	empty := Range(0, 0, 1)
+	if got := empty.SummaryStatistics(); got != (SummaryStatistics[int]{}) {
+		t.Errorf("SummaryStatistics() = %+v", got)
+	}
+	if _, ok := empty.Average().Value(); ok {
+		t.Error("Average() of empty stream should be false")
+	}
+	if got := empty.Sum(); got != 0 {
+		t.Errorf("Sum() = %v", got)
+	}
+}
+
+func TestNumberStream(t *testing.T) {
+	ages := FromValues(30, 20, 25, 40)
+	s := MapToInt(ages, func(e int) int64 {
+		return int64(e)
+	}).Filter(func(e int64) bool {
+		return e > 22
+	})
+	if got := s.Sum(); got != 95 {
+		t.Errorf("Sum() = %v", got)
+	}
+	if got, ok := s.Min().Value(); got != 25 || !ok {
+		t.Errorf("Min() = %v, %v", got, ok)
+	}
+	if got, ok := s.Max().Value(); got != 40 || !ok {
+		t.Errorf("Max() = %v, %v", got, ok)
+	}
+	if got := s.Sorted().Skip(1).Limit(1).ToSlice(); !reflect.DeepEqual(got, []int64{30}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+
+	f := MapToFloat(ages, func(e int) float64 {
+		return float64(e) / 10
+	}).Map(func(e float64) float64 {
+		return e * 2
+	})
+	if got, ok := f.Average().Value(); math.Abs(got-5.75) > 1e-9 || !ok {
+		t.Errorf("Average() = %v, %v", got, ok)
+	}
+	if got := Numbers(FromValues(math.NaN(), 1)).Sorted().Boxed().Count(); got != 2 {
+		t.Errorf("Count() = %v", got)
+	}
+	if got, _ := Numbers(FromValues(1, math.NaN())).Max().Value(); !math.IsNaN(got) {
+		t.Errorf("Max() = %v, NaN is the greatest", got)
+	}
+
+	// the parallel reductions agree with the statistics
+	par := Range[int64](-5000, 5000, 3).Parallel(4)
+	st := par.SummaryStatistics()
+	if got := par.Sum(); got != st.Sum {
+		t.Errorf("parallel Sum() = %v, want %v", got, st.Sum)
+	}
+	if got := par.Min().Get(); got != st.Min {
+		t.Errorf("parallel Min() = %v, want %v", got, st.Min)
+	}
+	if got := par.Max().Get(); got != st.Max {
+		t.Errorf("parallel Max() = %v, want %v", got, st.Max)
+	}
+	if got := par.Average().Get(); math.Abs(got-st.Mean) > 1e-9 {
+		t.Errorf("parallel Average() = %v, want %v", got, st.Mean)
+	}
+}
@@ -0,0 +1,231 @@
+package stream
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// the kinds of operations which the optimizer rewrites, the others are kindOther
+const (
+	kindOther = iota
+	kindFilter
+	kindSort
+	kindReverse
+	kindSkip
+	kindLimit
+)
+
+// optimize rewrites the stages to the equivalent ones which execute faster:
+// Filter moves ahead of Sort and Reverse, so fewer elements are sorted or reversed;
+// consecutive Skip and Limit are merged; Sort followed by Limit becomes a bounded heap,
+// which keeps only the elements in the limit. opts is modified in place
+func optimize(opts []stage) []stage {
+	pushDownFilters(opts)
+	return topK(mergeSkipLimit(opts))
+}
+
+// pushDownFilters moves each Filter ahead of the Sort and Reverse before it.
+// it is safe since a Filter keeps or drops an element regardless of the position.
+// FilterE is not moved, which changes the element reports the first error
+func pushDownFilters(opts []stage) {
+	for i := 1; i < len(opts); i++ {
+		for j := i; j > 0 && opts[j].kind == kindFilter && (opts[j-1].kind == kindSort || opts[j-1].kind == kindReverse); j-- {
+			opts[j-1], opts[j] = opts[j], opts[j-1]
+		}
+	}
+}
+
+// mergeSkipLimit merges consecutive Skip and Limit,
+// Skip(a).Skip(b) is Skip(a+b), Limit(a).Limit(b) is Limit(min(a, b)), Limit(a).Skip(b) is Skip(b).Limit(a-b)
+func mergeSkipLimit(opts []stage) []stage {
+	ret := make([]stage, 0, len(opts))
+	var push func(sg stage)
+	push = func(sg stage) {
+		if len(ret) == 0 {
+			ret = append(ret, sg)
+			return
+		}
+		last := ret[len(ret)-1]
+		if last.kind != kindSkip && last.kind != kindLimit || sg.kind != kindSkip && sg.kind != kindLimit {
+			ret = append(ret, sg)
+			return
+		}
+		// the negative number is the same as 0
+		a, b := nonNegative(last.param.(int)), nonNegative(sg.param.(int))
+		switch {
+		case last.kind == kindSkip && sg.kind == kindSkip:
+			ret = ret[:len(ret)-1]
+			if a > math.MaxInt-b {
+				// no stream has so many elements
+				push(skipStage(math.MaxInt))
+			} else {
+				push(skipStage(a + b))
+			}
+		case last.kind == kindLimit && sg.kind == kindLimit:
+			ret = ret[:len(ret)-1]
+			if b < a {
+				a = b
+			}
+			push(limitStage(a))
+		case last.kind == kindLimit && sg.kind == kindSkip:
+			ret = ret[:len(ret)-1]
+			push(skipStage(b))
+			push(limitStage(nonNegative(a - b)))
+		default:
+			ret = append(ret, sg)
+		}
+	}
+	for _, sg := range opts {
+		push(sg)
+	}
+	return ret
+}
+
+// topK replaces Sort followed by Limit(k) with TopK(k), and Sort followed by Skip(s).Limit(k) with TopK(s+k).
+// Limit(0) is kept, so the stage machine skips the source and the stages before it
+func topK(opts []stage) []stage {
+	ret := make([]stage, 0, len(opts))
+	for i := 0; i < len(opts); i++ {
+		sg := opts[i]
+		if sg.kind != kindSort || i+1 >= len(opts) {
+			ret = append(ret, sg)
+			continue
+		}
+		switch next := opts[i+1]; {
+		case next.kind == kindLimit && next.param.(int) <= 0,
+			next.kind == kindSkip && i+2 < len(opts) && opts[i+2].kind == kindLimit && opts[i+2].param.(int) <= 0:
+			ret = append(ret, sg)
+		case next.kind == kindLimit:
+			// the limit is dropped, TopK produces no more than k elements
+			ret = append(ret, topKStage(nonNegative(next.param.(int)), sg.param.(func(T, T) int)))
+			i++
+		case next.kind == kindSkip && i+2 < len(opts) && opts[i+2].kind == kindLimit:
+			s, k := nonNegative(next.param.(int)), nonNegative(opts[i+2].param.(int))
+			if s > math.MaxInt-k {
+				ret = append(ret, sg)
+				continue
+			}
+			ret = append(ret, topKStage(s+k, sg.param.(func(T, T) int)))
+		default:
+			ret = append(ret, sg)
+		}
+	}
+	return ret
+}
+
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// indexed is an element with its index in encounter order, which keeps the order of equal elements
+type indexed struct {
+	e T
+	i int
+}
+
+// topHeap is a max heap of the first k elements, the root is the greatest of them
+type topHeap struct {
+	list []indexed
+	k    int
+	cmp  func(T, T) int
+}
+
+func (h *topHeap) less(a indexed, b indexed) bool {
+	c := h.cmp(a.e, b.e)
+	return c < 0 || c == 0 && a.i < b.i
+}
+
+func (h *topHeap) Len() int           { return len(h.list) }
+func (h *topHeap) Less(i, j int) bool { return h.less(h.list[j], h.list[i]) }
+func (h *topHeap) Swap(i, j int)      { h.list[i], h.list[j] = h.list[j], h.list[i] }
+func (h *topHeap) Push(x interface{}) { h.list = append(h.list, x.(indexed)) }
+func (h *topHeap) Pop() interface{} {
+	ret := h.list[len(h.list)-1]
+	h.list = h.list[:len(h.list)-1]
+	return ret
+}
+
+// offer keeps e if it is one of the first k elements
+func (h *topHeap) offer(e indexed) {
+	switch {
+	case len(h.list) < h.k:
+		heap.Push(h, e)
+	case h.k > 0 && h.less(e, h.list[0]):
+		h.list[0] = e
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted returns the kept elements in order
+func (h *topHeap) sorted() []indexed {
+	sort.Slice(h.list, func(i, j int) bool {
+		return h.less(h.list[i], h.list[j])
+	})
+	return h.list
+}
+
+// newTopHeap creates a topHeap for n elements at most
+func newTopHeap(k int, n int, cmp func(T, T) int) *topHeap {
+	if n < k {
+		k = n
+	}
+	return &topHeap{list: make([]indexed, 0, k), k: k, cmp: cmp}
+}
+
+// topKStage sorts the first k elements by a bounded heap, the others are dropped.
+// the equal elements keep their encounter order, so it is the same as Sort and SortStable followed by Limit(k)
+func topKStage(k int, cmp func(T, T) int) stage {
+	return stage{
+		name: "TopK",
+		action: func(ele T, n int) (R, int) {
+			h := newTopHeap(k, n, cmp)
+			for i, e := range ele.([]T) {
+				h.offer(indexed{e: e, i: i})
+			}
+			list := h.sorted()
+			ret := make([]T, len(list))
+			for i, e := range list {
+				ret[i] = e.e
+			}
+			return ret, actionNext
+		},
+		parallel: func(chunks [][]T, workers int, stopped func() bool) []T {
+			offsets := make([]int, len(chunks))
+			for i := 1; i < len(chunks); i++ {
+				offsets[i] = offsets[i-1] + len(chunks[i-1])
+			}
+			parts := make([]*topHeap, len(chunks))
+			runWorkers(workers, len(chunks), func(c int) {
+				h := newTopHeap(k, len(chunks[c]), cmp)
+				for i, e := range chunks[c] {
+					if stopped() {
+						break
+					}
+					h.offer(indexed{e: e, i: offsets[c] + i})
+				}
+				parts[c] = h
+			})
+			n := 0
+			for _, part := range parts {
+				n += len(part.list)
+			}
+			h := newTopHeap(k, n, cmp)
+			for _, part := range parts {
+				for _, e := range part.list {
+					h.offer(e)
+				}
+			}
+			list := h.sorted()
+			ret := make([]T, len(list))
+			for i, e := range list {
+				ret[i] = e.e
+			}
+			return ret
+		},
+		stageFlag: stageStateful,
+	}
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// pair is an element with a key for sorting and an id to check the order of equal keys
+type pair struct {
+	key, id int
+}
+
+func byKey(left pair, right pair) int {
+	return left.key - right.key
+}
+
+func TestOptimizeTopK(t *testing.T) {
+	list := make([]pair, 1000)
+	for i := range list {
+		list[i] = pair{key: rand.Intn(50), id: i}
+	}
+	build := []func(s Stream[pair]) Stream[pair]{
+		func(s Stream[pair]) Stream[pair] { return s.SortStable(byKey).Limit(10) },
+		func(s Stream[pair]) Stream[pair] { return s.SortStable(byKey).Skip(5).Limit(10) },
+		func(s Stream[pair]) Stream[pair] { return s.SortStable(byKey).Limit(0) },
+		func(s Stream[pair]) Stream[pair] { return s.SortStable(byKey).Limit(2000) },
+	}
+	for i, f := range build {
+		want := f(FromSlice(list).Optimize(false)).ToSlice()
+		if got := f(FromSlice(list)).ToSlice(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: ToSlice() = %v, want %v", i, got, want)
+		}
+		if got := f(FromSlice(list).Parallel(4)).ToSlice(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: parallel ToSlice() = %v, want %v", i, got, want)
+		}
+	}
+	// Sort is not stable, but the keys are the same
+	got := FromSlice(list).Sort(byKey).Limit(20).ToSlice()
+	want := FromSlice(list).Optimize(false).Sort(byKey).Limit(20).ToSlice()
+	for i := range want {
+		if got[i].key != want[i].key {
+			t.Fatalf("ToSlice() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestOptimizeSkipLimit(t *testing.T) {
+	tests := []struct {
+		s    Stream[int]
+		plan string
+		want []int
+	}{
+		{FromValues(1, 2, 3, 4, 5, 6, 7, 8).Skip(1).Skip(2).Limit(4).Limit(3), "Source\n1. stateless: Skip -> Limit\n", []int{4, 5, 6}},
+		{FromValues(1, 2, 3, 4, 5, 6, 7, 8).Limit(5).Skip(2), "Source\n1. stateless: Skip -> Limit\n", []int{3, 4, 5}},
+		{FromValues(1, 2, 3, 4).Limit(2).Skip(3), "Source\n1. stateless: Skip -> Limit\n", []int{}},
+		{FromValues(1, 2, 3, 4).Skip(-1).Skip(1), "Source\n1. stateless: Skip\n", []int{2, 3, 4}},
+		{FromValues(1, 2, 3, 4).Slice(1, 2).Slice(1, 5), "Source\n1. stateless: Skip -> Limit\n", []int{3}},
+	}
+	for i, tt := range tests {
+		if got := tt.s.Explain(); got != tt.plan {
+			t.Errorf("%v: Explain() = %v, want %v", i, got, tt.plan)
+		}
+		if got := tt.s.ToSlice(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: ToSlice() = %v, want %v", i, got, tt.want)
+		}
+		if got := tt.s.Optimize(false).ToSlice(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: unoptimized ToSlice() = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestOptimizeFilter(t *testing.T) {
+	filtered := 0
+	s := FromValues(5, 2, 8, 1, 9).Sort(NaturalOrder[int]()).Reverse().Filter(func(e int) bool {
+		filtered++
+		return e > 2
+	}).Map(func(e int) int {
+		return e * 10
+	})
+	want := "Source\n" +
+		"1. stateless: Filter\n" +
+		"2. stateful: Sort\n" +
+		"3. stateful: Reverse\n" +
+		"4. stateless: Map\n"
+	if got := s.Explain(); got != want {
+		t.Errorf("Explain() = %v, want %v", got, want)
+	}
+	if got := s.ToSlice(); !reflect.DeepEqual(got, []int{90, 80, 50}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+	if filtered != 5 {
+		t.Errorf("filtered %v elements", filtered)
+	}
+
+	// FilterE is not moved
+	s = FromValues(2, 1).Sort(NaturalOrder[int]()).FilterE(func(e int) (bool, error) {
+		return true, nil
+	})
+	want = "Source\n" +
+		"1. stateful: Sort\n" +
+		"2. stateless: FilterE\n"
+	if got := s.Explain(); got != want {
+		t.Errorf("Explain() = %v, want %v", got, want)
+	}
+}
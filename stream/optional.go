@@ -0,0 +1,91 @@
+package stream
+
+import "fmt"
+
+// Optional is a value which may be absent, it tells an absent value apart from a nil or zero one.
+// the zero value is an absent Optional
+type Optional[T any] struct {
+	value   T
+	present bool
+}
+
+// OptionalOf returns a present Optional of value, which may be nil
+func OptionalOf[T any](value T) Optional[T] {
+	return Optional[T]{value: value, present: true}
+}
+
+// EmptyOptional returns an absent Optional
+func EmptyOptional[T any]() Optional[T] {
+	return Optional[T]{}
+}
+
+// optional returns a present Optional of value if present is true, otherwise an absent one
+func optional[T any](value T, present bool) Optional[T] {
+	if !present {
+		return Optional[T]{}
+	}
+	return OptionalOf(value)
+}
+
+// IsPresent reports whether the value is present
+func (o Optional[T]) IsPresent() bool {
+	return o.present
+}
+
+// Get returns the value, it panics if the value is absent
+func (o Optional[T]) Get() T {
+	if !o.present {
+		panic("stream: Get of an absent Optional")
+	}
+	return o.value
+}
+
+// Value returns the value and whether it is present, the value is zero if absent
+func (o Optional[T]) Value() (T, bool) {
+	return o.value, o.present
+}
+
+// OrElse returns the value if present, otherwise other
+func (o Optional[T]) OrElse(other T) T {
+	if !o.present {
+		return other
+	}
+	return o.value
+}
+
+// OrElseGet returns the value if present, otherwise the result of f
+func (o Optional[T]) OrElseGet(f Supplier[T]) T {
+	if !o.present {
+		return f()
+	}
+	return o.value
+}
+
+// Map returns the Optional of value mapped by f if present, otherwise an absent one.
+// use the function MapOptional if the result is another type
+func (o Optional[T]) Map(f UnaryOperator[T]) Optional[T] {
+	return MapOptional(o, Function[T, T](f))
+}
+
+// Filter returns o if the value is present and matches the condition, otherwise an absent one
+func (o Optional[T]) Filter(f Predicate[T]) Optional[T] {
+	if !o.present || !f(o.value) {
+		return Optional[T]{}
+	}
+	return o
+}
+
+func (o Optional[T]) String() string {
+	if !o.present {
+		return "Optional.empty"
+	}
+	return fmt.Sprintf("Optional[%v]", o.value)
+}
+
+// MapOptional returns the Optional of value of o mapped by f if present, otherwise an absent one
+func MapOptional[T, R any](o Optional[T], f Function[T, R]) Optional[R] {
+	if !o.present {
+		return Optional[R]{}
+	}
+	return OptionalOf(f(o.value))
+}
@@ -0,0 +1,93 @@
+package stream
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestOptional(t *testing.T) {
+	some, none := OptionalOf(2), EmptyOptional[int]()
+	if !some.IsPresent() || some.Get() != 2 || none.IsPresent() {
+		t.Errorf("IsPresent() = %v, %v", some.IsPresent(), none.IsPresent())
+	}
+	if got := some.OrElse(0); got != 2 {
+		t.Errorf("OrElse() = %v, want 2", got)
+	}
+	if got := none.OrElseGet(func() int { return 5 }); got != 5 {
+		t.Errorf("OrElseGet() = %v, want 5", got)
+	}
+	double := func(e int) int { return e * 2 }
+	if got := some.Map(double); got != OptionalOf(4) {
+		t.Errorf("Map() = %v", got)
+	}
+	if got := none.Map(double); got.IsPresent() {
+		t.Errorf("Map() of empty = %v", got)
+	}
+	odd := func(e int) bool { return e%2 == 1 }
+	if got := some.Filter(odd); got.IsPresent() {
+		t.Errorf("Filter() = %v", got)
+	}
+	if got := MapOptional(some, strconv.Itoa); got != OptionalOf("2") {
+		t.Errorf("MapOptional() = %v", got)
+	}
+	if some.String() != "Optional[2]" || none.String() != "Optional.empty" {
+		t.Errorf("String() = %v, %v", some, none)
+	}
+
+	// a nil value is present, which tells it apart from no element
+	nilFirst := FromValues[*int](nil, new(int)).FindFirst(func(e *int) bool { return true })
+	if !nilFirst.IsPresent() || nilFirst.Get() != nil {
+		t.Errorf("FindFirst() = %v", nilFirst)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Get() of empty should panic")
+		}
+	}()
+	none.Get()
+}
+
+func TestFirstLast(t *testing.T) {
+	s := FromValues(3, 1, 2)
+	if got := s.First(); got != OptionalOf(3) {
+		t.Errorf("First() = %v", got)
+	}
+	if got := s.Last(); got != OptionalOf(2) {
+		t.Errorf("Last() = %v", got)
+	}
+	if got := s.Parallel(2).Last(); got != OptionalOf(2) {
+		t.Errorf("parallel Last() = %v", got)
+	}
+	// First stops the infinite stream
+	if got := Iterate(1, func(e int) int { return e + 1 }).Skip(4).First(); got != OptionalOf(5) {
+		t.Errorf("First() = %v", got)
+	}
+	empty := FromValues[int]()
+	if empty.First().IsPresent() || empty.Last().IsPresent() {
+		t.Error("First() and Last() of empty stream should be empty")
+	}
+}
+
+func TestReduceOptional(t *testing.T) {
+	list := make([]int, 100)
+	for i := range list {
+		list[i] = i + 1
+	}
+	sum := func(a int, b int) int { return a + b }
+	if got := FromSlice(list).ReduceOptional(sum); got != OptionalOf(5050) {
+		t.Errorf("ReduceOptional() = %v", got)
+	}
+	if got := FromSlice(list).Parallel(4).ReduceOptional(sum); got != OptionalOf(5050) {
+		t.Errorf("parallel ReduceOptional() = %v", got)
+	}
+	// the results of chunks are combined in encounter order
+	concat := func(a string, b string) string { return a + b }
+	words := FromValues("a", "b", "c", "d", "e")
+	if got := words.Parallel(3).ReduceOptional(concat); got != OptionalOf("abcde") {
+		t.Errorf("parallel ReduceOptional() = %v", got)
+	}
+	if got := FromValues[int]().Parallel(2).ReduceOptional(sum); got.IsPresent() {
+		t.Errorf("ReduceOptional() of empty stream = %v", got)
+	}
+}
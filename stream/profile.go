@@ -0,0 +1,253 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// StageStats is the statistics of an operation in an instrumented execution
+type StageStats struct {
+	Name  string // Name is the name of operation, it is Source for the source of stream
+	Flag  string // Flag is the kind of operation, like stateless or stateful
+	Group int    // Group is the index of group in the execution plan from 1, it is 0 for the source
+	In    int64  // In is the number of elements received
+	Out   int64  // Out is the number of elements produced
+	// Time is the wall time spent in the operation, the time of upstream is not included.
+	// it is the sum of all workers when the operation executes parallel
+	Time time.Duration
+	// Allocs and Bytes are the heap allocations during the operation,
+	// which are counted process wide, so they are approximate if other goroutines allocate at the same time
+	Allocs uint64
+	Bytes  uint64
+}
+
+// Report is the report of an instrumented execution
+type Report struct {
+	Stages []StageStats  // Stages is the statistics of source and operations in execution order
+	Time   time.Duration // Time is the wall time of execution
+}
+
+// Executed keeps r as the report, so a *Report is a Hook which keeps the report of last execution
+func (p *Report) Executed(r Report) {
+	*p = r
+}
+
+// String formats the report as a table
+func (p *Report) String() string {
+	sb := &strings.Builder{}
+	w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tSTAGE\tFLAG\tIN\tOUT\tTIME\tALLOCS\tBYTES")
+	for _, st := range p.Stages {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%v\t%d\t%d\n", st.Group, st.Name, st.Flag, st.In, st.Out, st.Time, st.Allocs, st.Bytes)
+	}
+	w.Flush()
+	fmt.Fprintf(sb, "total %v\n", p.Time)
+	return sb.String()
+}
+
+// Hook receives the report when an instrumented execution finishes.
+// it must be safe for concurrent use if the stream is executed by many goroutines at the same time
+type Hook interface {
+	Executed(r Report)
+}
+
+// HookFunc is a function which is a Hook
+type HookFunc func(r Report)
+
+// Executed calls f(r)
+func (f HookFunc) Executed(r Report) {
+	f(r)
+}
+
+// Instrument records the statistics of each operation when the stream executes, and reports them to hook when
+// the terminal operation finishes. the operations are labeled with pprof label stream.stage,
+// so the CPU profiles can be attributed to them. it slows down the execution, use it for diagnosis only.
+// the instrumented streams which are sub-streams of others, like the streams of FlatMap and Concat,
+// are reported when they have no more element or fail, so they are not reported if they are not pulled to the end
+func (s *stream[E]) Instrument(hook Hook) Stream[E] {
+	ret := *s
+	ret.hook = hook
+	return &ret
+}
+
+// profile records the statistics of stages in an execution
+type profile struct {
+	stats []*stageStats
+	base  context.Context // base is the context of the goroutine labels when the execution starts
+	start time.Time
+}
+
+// stageStats is the statistics of a stage, which is updated by workers at the same time
+type stageStats struct {
+	name, flag string
+	group      int
+	in, out    int64
+	nanos      int64
+	allocs     uint64
+	bytes      uint64
+	labels     context.Context // labels is the context of the goroutine labels set during the calls of stage
+}
+
+// report returns the report of profile
+func (p *profile) report() Report {
+	ret := Report{Stages: make([]StageStats, len(p.stats)), Time: time.Since(p.start)}
+	for i, st := range p.stats {
+		ret.Stages[i] = StageStats{
+			Name:   st.name,
+			Flag:   st.flag,
+			Group:  st.group,
+			In:     atomic.LoadInt64(&st.in),
+			Out:    atomic.LoadInt64(&st.out),
+			Time:   time.Duration(atomic.LoadInt64(&st.nanos)),
+			Allocs: atomic.LoadUint64(&st.allocs),
+			Bytes:  atomic.LoadUint64(&st.bytes),
+		}
+	}
+	return ret
+}
+
+// instrument wraps the source and the stages of m, which record their statistics in the returned profile
+func instrument(m *stageMachine) *profile {
+	p := &profile{base: context.Background(), start: time.Now()}
+	if m.ctx != nil {
+		p.base = m.ctx
+	}
+	if labels, ok := activeLabels.Load(goid()); ok {
+		// the execution is nested in a measured call, like a sub-stream of FlatMap, so it is labeled in that stage
+		p.base = labels.(context.Context)
+	}
+	newStats := func(name string, flag string, group int) *stageStats {
+		st := &stageStats{name: name, flag: flag, group: group}
+		st.labels = pprof.WithLabels(p.base, pprof.Labels("stream.stage", name))
+		p.stats = append(p.stats, st)
+		return st
+	}
+
+	src := newStats("Source", "source", 0)
+	m.src = p.countOut(src, m.src)
+	for g, s := range m.stages {
+		for i := range s {
+			sg := &s[i]
+			st := newStats(sg.name, stageFlagNames[sg.stageFlag], g+1)
+			action, parallel, flush := sg.action, sg.parallel, sg.flush
+			sg.action = func(e T, i int) (r R, signal int) {
+				p.measure(st, func() {
+					r, signal = action(e, i)
+				})
+				p.count(st, sg.stageFlag, e, r, signal)
+				if signal == actionExpand {
+					r = p.countOut(st, r.(iterator))
+				}
+				return
+			}
+			if parallel != nil {
+				sg.parallel = func(chunks [][]T, workers int, stopped func() bool) (ret []T) {
+					p.measure(st, func() {
+						ret = parallel(chunks, workers, stopped)
+					})
+					for _, c := range chunks {
+						atomic.AddInt64(&st.in, int64(len(c)))
+					}
+					atomic.AddInt64(&st.out, int64(len(ret)))
+					return
+				}
+			}
+			if flush != nil {
+				sg.flush = func() (it iterator) {
+					p.measure(st, func() {
+						it = flush()
+					})
+					if it != nil {
+						it = p.countOut(st, it)
+					}
+					return
+				}
+			}
+		}
+	}
+	return p
+}
+
+// count counts the elements received and produced by a call of action
+func (p *profile) count(st *stageStats, flag int, e T, r R, signal int) {
+	if flag == stageStateless || flag == stageShortcut {
+		atomic.AddInt64(&st.in, 1)
+	} else if prod, ok := e.([]T); ok {
+		atomic.AddInt64(&st.in, int64(len(prod)))
+	}
+	switch signal {
+	case actionNext, actionLast:
+		if flag == stageStateless {
+			atomic.AddInt64(&st.out, 1)
+		} else if prod, ok := r.([]T); ok && flag == stageStateful {
+			atomic.AddInt64(&st.out, int64(len(prod)))
+		}
+	}
+}
+
+// countOut returns an iterator of it, which counts the elements as produced by the stage, and the time pulling them
+func (p *profile) countOut(st *stageStats, it iterator) iterator {
+	return func() (e T, ok bool) {
+		p.measure(st, func() {
+			e, ok = it()
+		})
+		if ok {
+			atomic.AddInt64(&st.out, 1)
+		}
+		return
+	}
+}
+
+// activeLabels is the context of the goroutine labels set by measure on each goroutine, keyed by the goroutine id.
+// pprof can't read the labels of a goroutine, so they are kept here to be restored after the nested measured calls
+var activeLabels sync.Map
+
+// goid returns the id of the current goroutine, which is parsed from the header of its stack like "goroutine 7 ["
+func goid() uint64 {
+	var buf [32]byte
+	n := runtime.Stack(buf[:], false)
+	id := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(id, ' '); i >= 0 {
+		id = id[:i]
+	}
+	ret, _ := strconv.ParseUint(string(id), 10, 64)
+	return ret
+}
+
+// measure calls f with the pprof labels of stage, and records the time and allocations of f.
+// the time of nested measured calls, like the upstream pulled by an expanded iterator, is included.
+// the labels active before are restored after f, so the enclosing measured call keeps its labels
+func (p *profile) measure(st *stageStats, f func()) {
+	samples := []metrics.Sample{{Name: "/gc/heap/allocs:objects"}, {Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(samples)
+	allocs, bytes := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	start := time.Now()
+	id := goid()
+	prev, nested := activeLabels.Load(id)
+	activeLabels.Store(id, st.labels)
+	pprof.SetGoroutineLabels(st.labels)
+	defer func() {
+		if nested {
+			activeLabels.Store(id, prev)
+			pprof.SetGoroutineLabels(prev.(context.Context))
+		} else {
+			activeLabels.Delete(id)
+			pprof.SetGoroutineLabels(p.base)
+		}
+		atomic.AddInt64(&st.nanos, int64(time.Since(start)))
+		metrics.Read(samples)
+		atomic.AddUint64(&st.allocs, samples[0].Value.Uint64()-allocs)
+		atomic.AddUint64(&st.bytes, samples[1].Value.Uint64()-bytes)
+	}()
+	f()
+}
@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"context"
+	"reflect"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+// counts returns the name, in and out of each stage in the report
+func counts(r Report) [][]interface{} {
+	ret := make([][]interface{}, len(r.Stages))
+	for i, st := range r.Stages {
+		ret[i] = []interface{}{st.Group, st.Name, st.Flag, st.In, st.Out}
+	}
+	return ret
+}
+
+func TestInstrument(t *testing.T) {
+	report := &Report{}
+	got := FromValues(1, 2, 3, 4, 5, 6).Instrument(report).Filter(func(e int) bool {
+		return e%2 == 0
+	}).FlatMapSlice(func(e int) []int {
+		return []int{e, e}
+	}).Sort(NaturalOrder[int]()).Limit(3).ToSlice()
+	if !reflect.DeepEqual(got, []int{2, 2, 4}) {
+		t.Fatalf("ToSlice() = %v", got)
+	}
+	want := [][]interface{}{
+		{0, "Source", "source", int64(0), int64(6)},
+		{1, "Filter", "stateless", int64(6), int64(3)},
+		{1, "FlatMapSlice", "stateless", int64(3), int64(6)},
+		{2, "TopK", "stateful", int64(6), int64(3)},
+		{3, "ToSlice", "non-shortcut", int64(3), int64(0)},
+	}
+	if got := counts(*report); !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %v, want %v", got, want)
+	}
+	if report.Time <= 0 || report.Stages[3].Time <= 0 {
+		t.Errorf("report has no time %v", report)
+	}
+	if s := report.String(); !strings.Contains(s, "FlatMapSlice") || !strings.HasPrefix(s, "GROUP") {
+		t.Errorf("String() = %v", s)
+	}
+
+	// the hook receives the report of each execution
+	executions := 0
+	s := Iterate(1, func(e int) int { return e + 1 }).Instrument(HookFunc(func(r Report) {
+		executions++
+		if r.Stages[0].Out != 4 {
+			t.Errorf("source produced %v elements, want 4", r.Stages[0].Out)
+		}
+	})).Map(func(e int) int {
+		return e * 2
+	})
+	s.AnyMatch(func(e int) bool { return e == 8 })
+	s.FindFirst(func(e int) bool { return e == 8 })
+	if executions != 2 {
+		t.Errorf("hook is called %v times, want 2", executions)
+	}
+}
+
+func TestInstrumentParallel(t *testing.T) {
+	list := make([]int, 1000)
+	report := &Report{}
+	count := FromSlice(list).Parallel(4).Instrument(report).Map(func(e int) int {
+		return e + 1
+	}).Filter(func(e int) bool {
+		return false
+	}).Count()
+	if count != 0 {
+		t.Errorf("Count() = %v", count)
+	}
+	want := [][]interface{}{
+		{0, "Source", "source", int64(0), int64(1000)},
+		{1, "Map", "stateless", int64(1000), int64(1000)},
+		{1, "Filter", "stateless", int64(1000), int64(0)},
+		{2, "Count", "non-shortcut", int64(0), int64(0)},
+	}
+	if got := counts(*report); !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %v, want %v", got, want)
+	}
+}
+
+func TestInstrumentNested(t *testing.T) {
+	// the sub-streams are reported when they are pulled to the end
+	reports := make([]Report, 0)
+	hook := HookFunc(func(r Report) {
+		reports = append(reports, r)
+	})
+	got := FromValues(1, 2).Instrument(hook).Concat(FromValues(3)).ToSlice()
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) || len(reports) != 2 {
+		t.Fatalf("ToSlice() = %v, reported %v times", got, len(reports))
+	}
+	if sub, outer := reports[0].Stages[0].Out, reports[1].Stages[0].Out; sub != 2 || outer != 3 {
+		t.Errorf("the sources produced %v and %v elements", sub, outer)
+	}
+
+	// the labels of the outer stage are restored after the nested execution
+	stage := ""
+	FromValues(1).Instrument(&Report{}).Map(func(e int) int {
+		FromValues(2).Instrument(&Report{}).Map(func(e int) int {
+			return e
+		}).ToSlice()
+		if labels, ok := activeLabels.Load(goid()); ok {
+			stage, _ = pprof.Label(labels.(context.Context), "stream.stage")
+		}
+		return e
+	}).ToSlice()
+	if stage != "Map" {
+		t.Errorf("labels after the nested execution = %q, want Map", stage)
+	}
+	if _, ok := activeLabels.Load(goid()); ok {
+		t.Error("labels are active after the execution")
+	}
+}
@@ -0,0 +1,499 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Query compiles the SQL-like query into the operations of s, and returns the stream of selected rows.
+// each row is a map from the column names to the values, the name of column is its alias, or the text of it
+// like "Position.City" or "COUNT(*)". the grammar is
+//
+//	SELECT * | column [AS alias], ...
+//	[WHERE condition]
+//	[GROUP BY field, ...]
+//	[ORDER BY field | column [ASC | DESC], ...]
+//	[LIMIT n] [OFFSET n]
+//
+// a column is a field or an aggregate function COUNT(*), COUNT(field), SUM(field) or AVG(field).
+// a condition is a comparison of field and value by =, !=, <>, <, <=, >, >=, IS [NOT] NULL or [NOT] IN (values),
+// the conditions are combined by AND, OR, NOT and parentheses. a value is a number, a 'string', TRUE, FALSE or NULL.
+//
+// the fields are read by Field, so nil on the path is NULL. the comparisons with NULL are false except IS NULL,
+// and NULL is the greatest in order. when the query has GROUP BY or aggregate functions, the elements are grouped
+// into a row for each group in encounter order, which has the fields of GROUP BY and the aggregate functions,
+// ORDER BY sorts the rows by their columns then. the aggregate functions ignore NULL, SUM is an int64 of integers or
+// a float64 if any number is a float, and AVG is a float64 or NULL if no number.
+//
+// it returns the error if the query is invalid, or the fields are not exported fields of E.
+// the fields of maps and interfaces can't be checked, they are NULL if absent
+func Query[E any](s Stream[E], query string) (Stream[map[string]interface{}], error) {
+	q, err := compileQuery[E](query)
+	if err != nil {
+		return nil, err
+	}
+	return retype[map[string]interface{}](q.plan(retype[interface{}](s.(*stream[E])), true)), nil
+}
+
+// QueryElements is like Query, but it returns the elements themselves, so the query must be SELECT *
+// without GROUP BY and aggregate functions
+func QueryElements[E any](s Stream[E], query string) (Stream[E], error) {
+	q, err := compileQuery[E](query)
+	if err != nil {
+		return nil, err
+	}
+	if !q.star || q.grouped() {
+		return nil, errors.New("stream: query: QueryElements requires SELECT * without GROUP BY and aggregate functions")
+	}
+	return retype[E](q.plan(retype[interface{}](s.(*stream[E])), false)), nil
+}
+
+// compileQuery parses the query and checks it with the fields of E
+func compileQuery[E any](query string) (*parsedQuery, error) {
+	q, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return q, q.check(reflect.TypeOf((*E)(nil)).Elem())
+}
+
+// check checks the fields and the columns of query, the fields are checked only if t is not an interface
+func (q *parsedQuery) check(t reflect.Type) error {
+	if t.Kind() != reflect.Interface {
+		for _, path := range q.paths {
+			if _, err := accessor(t, path); err != nil {
+				return fmt.Errorf("stream: query: unknown field %s: %v", path, errors.Unwrap(err))
+			}
+		}
+	}
+	if !q.grouped() {
+		for _, o := range q.orderBy {
+			if c := q.column(o.name); c >= 0 || t.Kind() == reflect.Interface {
+				continue
+			}
+			if _, err := accessor(t, o.name); err != nil {
+				return fmt.Errorf("stream: query: unknown field %s: %v", o.name, errors.Unwrap(err))
+			}
+		}
+		return nil
+	}
+	if q.star {
+		return errors.New("stream: query: SELECT * can't be grouped")
+	}
+	for _, c := range q.columns {
+		if c.agg == "" && !contains(q.groupBy, c.path) {
+			return fmt.Errorf("stream: query: column %s must be in GROUP BY or an aggregate function", c.name)
+		}
+	}
+	for _, o := range q.orderBy {
+		if q.column(o.name) < 0 {
+			return fmt.Errorf("stream: query: ORDER BY %s is not a column of the grouped rows", o.name)
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// column returns the index of column named name, -1 if no such column
+func (q *parsedQuery) column(name string) int {
+	for i, c := range q.columns {
+		if c.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// plan appends the operations of query to s, the rows are projected into maps if project is true
+func (q *parsedQuery) plan(s *stream[T], project bool) *stream[T] {
+	ret := s
+	if q.where != nil {
+		ret = ret.Filter(q.where).(*stream[T])
+		ret.opts[len(ret.opts)-1].name = "Where"
+	}
+	if q.grouped() {
+		ret = ret.appendStage(q.groupStage())
+	}
+	if len(q.orderBy) > 0 {
+		ret = ret.sort(q.comparator(), true)
+		ret.opts[len(ret.opts)-1].name = "OrderBy"
+	}
+	if q.offset > 0 {
+		ret = ret.Skip(q.offset).(*stream[T])
+	}
+	if q.limit >= 0 {
+		ret = ret.Limit(q.limit).(*stream[T])
+	}
+	if project && !q.grouped() {
+		project := q.projection()
+		ret = ret.addStage("Select", func(ele T, i int) (R, int) {
+			return project(ele), actionNext
+		}, stageStateless)
+	}
+	return ret
+}
+
+// comparator returns the Comparator of ORDER BY, which compares the columns of rows if the query is grouped,
+// otherwise the fields of elements. the columns selected from the fields can be ordered by their aliases
+func (q *parsedQuery) comparator() Comparator[T] {
+	keys := make([]Function[T, R], len(q.orderBy))
+	for i, o := range q.orderBy {
+		name := o.name
+		if c := q.column(name); q.grouped() {
+			keys[i] = func(e T) R {
+				return e.(map[string]T)[name]
+			}
+			continue
+		} else if c >= 0 && q.columns[c].agg == "" {
+			name = q.columns[c].path
+		}
+		keys[i] = Field(name)
+	}
+	return func(left T, right T) int {
+		for i, key := range keys {
+			if c := compareNullsLast(key(left), key(right)); c != 0 {
+				if q.orderBy[i].desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return 0
+	}
+}
+
+// projection returns the function which selects the row of columns from an element
+func (q *parsedQuery) projection() func(e T) map[string]T {
+	if q.star {
+		return fieldsOf()
+	}
+	values := make([]Function[T, R], len(q.columns))
+	for i, c := range q.columns {
+		values[i] = Field(c.path)
+	}
+	return func(e T) map[string]T {
+		ret := make(map[string]T, len(q.columns))
+		for i, c := range q.columns {
+			ret[c.name] = values[i](e)
+		}
+		return ret
+	}
+}
+
+// fieldGetters is the getters of the exported fields of a struct type, which read the fields like Field
+type fieldGetters struct {
+	t     reflect.Type
+	names []string
+	gets  []getter
+}
+
+// newFieldGetters returns the getters of the exported fields of struct type t
+func newFieldGetters(t reflect.Type) *fieldGetters {
+	ret := &fieldGetters{t: t}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" {
+			// an exported field always has a getter
+			get, _ := accessor(t, f.Name)
+			ret.names = append(ret.names, f.Name)
+			ret.gets = append(ret.gets, get)
+		}
+	}
+	return ret
+}
+
+// fieldsOf returns the function which selects the exported fields of struct, or the entries of map with string keys.
+// the getters of fields are compiled once for each struct type, and the function keeps the getters of the last type
+func fieldsOf() func(e T) map[string]T {
+	var last atomic.Value // last is the *fieldGetters of the last struct type
+	return func(e T) map[string]T {
+		ret := make(map[string]T)
+		v := reflect.ValueOf(e)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return ret
+			}
+			v = v.Elem()
+		}
+		switch {
+		case v.Kind() == reflect.Struct:
+			fields, _ := last.Load().(*fieldGetters)
+			if fields == nil || fields.t != v.Type() {
+				fields = newFieldGetters(v.Type())
+				last.Store(fields)
+			}
+			for i, name := range fields.names {
+				ret[name] = nil
+				if f, ok := fields.gets[i](v); ok {
+					ret[name] = f.Interface()
+				}
+			}
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			for it := v.MapRange(); it.Next(); {
+				ret[it.Key().String()] = it.Value().Interface()
+			}
+		default:
+			panic(fmt.Errorf("stream: query: can't select * from %T", e))
+		}
+		return ret
+	}
+}
+
+// aggregate accumulates the values of an aggregate function
+type aggregate struct {
+	count   int
+	ints    int64
+	float   float64
+	isFloat bool
+}
+
+func (a *aggregate) add(fn string, v T) {
+	if v == nil {
+		return
+	}
+	a.count++
+	if fn == "COUNT" {
+		return
+	}
+	switch n := reflect.ValueOf(v); kindOf(n) {
+	case reflect.Int64:
+		a.ints += n.Int()
+	case reflect.Uint64:
+		a.ints += int64(n.Uint())
+	case reflect.Float64:
+		a.float += n.Float()
+		a.isFloat = true
+	default:
+		panic(fmt.Errorf("stream: query: %s of non-number %v", fn, v))
+	}
+}
+
+func (a *aggregate) result(fn string) T {
+	switch {
+	case fn == "COUNT":
+		return a.count
+	case fn == "AVG" && a.count == 0:
+		return nil
+	case fn == "AVG":
+		return (float64(a.ints) + a.float) / float64(a.count)
+	case a.isFloat:
+		return float64(a.ints) + a.float
+	}
+	return a.ints
+}
+
+// keyPair chains the values of GROUP BY into a comparable tuple
+type keyPair struct {
+	head T
+	tail T
+}
+
+// canonicalKey is the canonical text of a value which can't be compared by ==
+type canonicalKey string
+
+// groupKind remembers whether the values of the last type read are plain, so it is checked once for a type
+type groupKind struct {
+	t     reflect.Type
+	plain bool
+}
+
+// value returns the comparable value of v to group by, the equal values have equal results.
+// the plain values are returned as they are, the others are converted to their canonical text
+func (k *groupKind) value(v T) T {
+	if v == nil {
+		return nil
+	}
+	if t := reflect.TypeOf(v); t != k.t {
+		k.t, k.plain = t, plain(t)
+	}
+	// v is not equal to itself if it has NaN
+	if k.plain && v == v {
+		return v
+	}
+	sb := strings.Builder{}
+	sb.WriteString(k.t.String())
+	writeCanonical(&sb, reflect.ValueOf(v), make(map[reference]int))
+	return canonicalKey(sb.String())
+}
+
+// plain reports whether the values of t are equal if and only if they are ==,
+// the pointers and interfaces in values are compared by address or dynamic type, so they are not plain
+func plain(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return plain(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !plain(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// reference is a pointer, slice or map being written by writeCanonical
+type reference struct {
+	t   reflect.Type
+	ptr uintptr
+	len int
+}
+
+// writeCanonical writes the canonical text of v, the pointers are dereferenced and the entries of maps are sorted.
+// path is the depths of the references being written, a reference on it is written as its depth, so the cycles end
+func writeCanonical(sb *strings.Builder, v reflect.Value, path map[reference]int) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			sb.WriteString("nil")
+			return
+		}
+		ref := reference{t: v.Type(), ptr: v.Pointer()}
+		if v.Kind() == reflect.Slice {
+			ref.len = v.Len()
+		}
+		if depth, ok := path[ref]; ok {
+			fmt.Fprintf(sb, "^%d", depth)
+			return
+		}
+		path[ref] = len(path)
+		defer delete(path, ref)
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		sb.WriteString("nil")
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			sb.WriteString("nil")
+			return
+		}
+		sb.WriteByte('&')
+		writeCanonical(sb, v.Elem(), path)
+	case reflect.Struct:
+		sb.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			writeCanonical(sb, v.Field(i), path)
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		sb.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			writeCanonical(sb, v.Index(i), path)
+			sb.WriteByte(',')
+		}
+		sb.WriteByte(']')
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			entry := strings.Builder{}
+			writeCanonical(&entry, it.Key(), path)
+			entry.WriteByte(':')
+			writeCanonical(&entry, it.Value(), path)
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		sb.WriteString("map[")
+		sb.WriteString(strings.Join(entries, ","))
+		sb.WriteByte(']')
+	default:
+		// the strings are quoted, so the separators in them are not ambiguous
+		fmt.Fprintf(sb, "%#v", v)
+	}
+}
+
+// groupStage groups the elements by the fields of GROUP BY, and produces a row for each group in encounter order.
+// all elements are in a group if no GROUP BY, so the row is produced even if no element
+func (q *parsedQuery) groupStage() stage {
+	keys := make([]Function[T, R], len(q.groupBy))
+	for i, path := range q.groupBy {
+		keys[i] = Field(path)
+	}
+	values := make([]Function[T, R], len(q.columns))
+	for i, c := range q.columns {
+		if c.agg != "" && c.path != "" {
+			values[i] = Field(c.path)
+		}
+	}
+	type group struct {
+		keys []T
+		aggs []aggregate
+	}
+	return stage{
+		name: "GroupBy",
+		action: func(ele T, n int) (R, int) {
+			groups := make([]*group, 0)
+			index := make(map[T]*group)
+			kinds := make([]groupKind, len(keys))
+			for _, e := range ele.([]T) {
+				ks := make([]T, len(keys))
+				// id is the tuple of the values of keys, the dynamic types are compared too, so 1 and "1" are different groups
+				var id T
+				for i := len(keys) - 1; i >= 0; i-- {
+					ks[i] = keys[i](e)
+					if v := kinds[i].value(ks[i]); i == len(keys)-1 {
+						id = v
+					} else {
+						id = keyPair{head: v, tail: id}
+					}
+				}
+				g, ok := index[id]
+				if !ok {
+					g = &group{keys: ks, aggs: make([]aggregate, len(q.columns))}
+					index[id] = g
+					groups = append(groups, g)
+				}
+				for i, c := range q.columns {
+					switch {
+					case c.agg == "":
+					case values[i] == nil:
+						// COUNT(*) counts the nil elements too
+						g.aggs[i].count++
+					default:
+						g.aggs[i].add(c.agg, values[i](e))
+					}
+				}
+			}
+			if len(keys) == 0 && len(groups) == 0 {
+				groups = append(groups, &group{aggs: make([]aggregate, len(q.columns))})
+			}
+
+			rows := make([]T, len(groups))
+			for r, g := range groups {
+				row := make(map[string]T, len(q.columns))
+				for i, c := range q.columns {
+					if c.agg != "" {
+						row[c.name] = g.aggs[i].result(c.agg)
+						continue
+					}
+					for k, path := range q.groupBy {
+						if path == c.path {
+							row[c.name] = g.keys[k]
+						}
+					}
+				}
+				rows[r] = row
+			}
+			return rows, actionNext
+		},
+		stageFlag: stageStateful,
+	}
+}
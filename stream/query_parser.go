@@ -0,0 +1,513 @@
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the kinds of tokens of query
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int // pos is the byte offset of token in query
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return t.text
+}
+
+// keywords can't be used as fields or aliases
+var keywords = map[string]bool{
+	"SELECT": true, "WHERE": true, "GROUP": true, "ORDER": true, "BY": true, "LIMIT": true, "OFFSET": true,
+	"AND": true, "OR": true, "NOT": true, "AS": true, "ASC": true, "DESC": true, "IS": true, "IN": true,
+	"NULL": true, "TRUE": true, "FALSE": true,
+}
+
+// aggregates are the aggregate functions of query
+var aggregates = map[string]bool{"COUNT": true, "SUM": true, "AVG": true}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lex splits query into tokens, the field paths like Position.City are single tokens
+func lex(query string) ([]token, error) {
+	ret := make([]token, 0)
+	for i := 0; i < len(query); {
+		c := query[i]
+		j := i + 1
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case isLetter(c):
+			for j < len(query) && (isLetter(query[j]) || isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+			ret = append(ret, token{kind: tokenIdent, text: query[i:j], pos: i})
+		case isDigit(c) || c == '-' && j < len(query) && isDigit(query[j]):
+			for j < len(query) && (isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+			ret = append(ret, token{kind: tokenNumber, text: query[i:j], pos: i})
+		case c == '\'':
+			// the quote in string is escaped by doubling it
+			sb := strings.Builder{}
+			for ; ; j++ {
+				if j >= len(query) {
+					return nil, fmt.Errorf("stream: query: unterminated string at %d", i)
+				}
+				if query[j] == '\'' {
+					if j+1 < len(query) && query[j+1] == '\'' {
+						j++
+					} else {
+						break
+					}
+				}
+				sb.WriteByte(query[j])
+			}
+			j++
+			ret = append(ret, token{kind: tokenString, text: sb.String(), pos: i})
+		default:
+			if s := query[i:minInt(i+2, len(query))]; s == "<=" || s == ">=" || s == "!=" || s == "<>" {
+				j = i + 2
+			} else if !strings.ContainsRune("=<>(),*", rune(c)) {
+				return nil, fmt.Errorf("stream: query: unexpected character %q at %d", c, i)
+			}
+			ret = append(ret, token{kind: tokenSymbol, text: query[i:j], pos: i})
+		}
+		i = j
+	}
+	return append(ret, token{kind: tokenEOF, pos: len(query)}), nil
+}
+
+// minInt returns the smaller of a and b
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// queryColumn is a column of the rows selected by query
+type queryColumn struct {
+	name string // name is the key of column in rows, the alias or the text of column
+	path string // path is the field, it is empty for COUNT(*)
+	agg  string // agg is COUNT, SUM or AVG, it is empty if the column is a field
+}
+
+// queryOrder is an item of ORDER BY
+type queryOrder struct {
+	name string // name is the field, or the column of rows if the query is grouped
+	desc bool
+}
+
+// parsedQuery is the syntax tree of query
+type parsedQuery struct {
+	star    bool // star is true if the query selects *
+	columns []queryColumn
+	where   Predicate[T]
+	groupBy []string
+	orderBy []queryOrder
+	limit   int // limit is -1 if no LIMIT
+	offset  int
+	paths   []string // paths are the fields read from elements, they are checked before the query executes
+}
+
+// grouped reports whether the query groups the elements into rows by GROUP BY or aggregate functions
+func (q *parsedQuery) grouped() bool {
+	if len(q.groupBy) > 0 {
+		return true
+	}
+	for _, c := range q.columns {
+		if c.agg != "" {
+			return true
+		}
+	}
+	return false
+}
+
+type parser struct {
+	tokens []token
+	i      int
+	query  *parsedQuery
+}
+
+// parseQuery parses query like
+//
+//	SELECT Id, Name WHERE Age > 22 AND Position.Country = 'China' ORDER BY Id LIMIT 10
+//
+// the keywords are case-insensitive, the fields are case-sensitive
+func parseQuery(query string) (*parsedQuery, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, query: &parsedQuery{limit: -1}}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p.query, nil
+}
+
+func (p *parser) parse() (err error) {
+	q := p.query
+	if err = p.expect("SELECT"); err != nil {
+		return
+	}
+	if err = p.parseColumns(); err != nil {
+		return
+	}
+	if p.keyword("WHERE") {
+		if q.where, err = p.parseOr(); err != nil {
+			return
+		}
+	}
+	if p.keyword("GROUP") {
+		if err = p.expect("BY"); err != nil {
+			return
+		}
+		for {
+			path, err := p.field()
+			if err != nil {
+				return err
+			}
+			q.groupBy = append(q.groupBy, path)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("ORDER") {
+		if err = p.expect("BY"); err != nil {
+			return
+		}
+		for {
+			tok := p.next()
+			if tok.kind != tokenIdent || keywords[strings.ToUpper(tok.text)] {
+				return p.errorf(tok, "expected field or column")
+			}
+			order := queryOrder{name: tok.text}
+			if p.keyword("DESC") {
+				order.desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			q.orderBy = append(q.orderBy, order)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("LIMIT") {
+		if q.limit, err = p.count(); err != nil {
+			return
+		}
+	}
+	if p.keyword("OFFSET") {
+		if q.offset, err = p.count(); err != nil {
+			return
+		}
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return p.errorf(tok, "unexpected %v", tok)
+	}
+	return nil
+}
+
+func (p *parser) parseColumns() error {
+	q := p.query
+	if p.symbol("*") {
+		q.star = true
+		return nil
+	}
+	names := make(map[string]bool)
+	for {
+		var c queryColumn
+		if tok, next := p.peek(), p.tokens[minInt(p.i+1, len(p.tokens)-1)]; tok.kind == tokenIdent &&
+			aggregates[strings.ToUpper(tok.text)] && next.kind == tokenSymbol && next.text == "(" {
+			p.i += 2
+			c.agg = strings.ToUpper(tok.text)
+			if c.agg == "COUNT" && p.symbol("*") {
+				c.name = "COUNT(*)"
+			} else {
+				path, err := p.field()
+				if err != nil {
+					return err
+				}
+				c.name, c.path = c.agg+"("+path+")", path
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return err
+			}
+		} else {
+			path, err := p.field()
+			if err != nil {
+				return err
+			}
+			c.name, c.path = path, path
+		}
+		if p.keyword("AS") {
+			tok := p.next()
+			if tok.kind != tokenIdent || keywords[strings.ToUpper(tok.text)] {
+				return p.errorf(tok, "expected alias")
+			}
+			c.name = tok.text
+		}
+		if names[c.name] {
+			return fmt.Errorf("stream: query: duplicate column %s", c.name)
+		}
+		names[c.name] = true
+		q.columns = append(q.columns, c)
+		if !p.symbol(",") {
+			return nil
+		}
+	}
+}
+
+// parseOr parses the conditions joined by OR, which has the lowest precedence
+func (p *parser) parseOr() (Predicate[T], error) {
+	left, err := p.parseAnd()
+	for err == nil && p.keyword("OR") {
+		var right Predicate[T]
+		if right, err = p.parseAnd(); err == nil {
+			l := left
+			left = func(e T) bool {
+				return l(e) || right(e)
+			}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseAnd() (Predicate[T], error) {
+	left, err := p.parseNot()
+	for err == nil && p.keyword("AND") {
+		var right Predicate[T]
+		if right, err = p.parseNot(); err == nil {
+			l := left
+			left = func(e T) bool {
+				return l(e) && right(e)
+			}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) parseNot() (Predicate[T], error) {
+	if !p.keyword("NOT") {
+		return p.parseComparison()
+	}
+	f, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return func(e T) bool {
+		return !f(e)
+	}, nil
+}
+
+// parseComparison parses a condition in parentheses, or a comparison of field like
+// Age > 22, Name IS NOT NULL or Age NOT IN (20, 30)
+func (p *parser) parseComparison() (Predicate[T], error) {
+	if p.symbol("(") {
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expectSymbol(")")
+	}
+	path, err := p.field()
+	if err != nil {
+		return nil, err
+	}
+	get := Field(path)
+	// test tests the field by f, the comparisons with NULL are false even if they are negated
+	test := func(f Predicate[T], negate bool) Predicate[T] {
+		return func(e T) bool {
+			v := get(e)
+			return v != nil && f(v) != negate
+		}
+	}
+
+	if p.keyword("IS") {
+		negate := p.keyword("NOT")
+		if err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return func(e T) bool {
+			return (get(e) == nil) != negate
+		}, nil
+	}
+	if negate := p.keyword("NOT"); negate || p.keyword("IN") {
+		if negate {
+			if err := p.expect("IN"); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		set := make([]Predicate[T], 0)
+		for {
+			v, err := p.literal()
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, Eq(v))
+			if !p.symbol(",") {
+				break
+			}
+		}
+		return test(func(e T) bool {
+			for _, eq := range set {
+				if eq(e) {
+					return true
+				}
+			}
+			return false
+		}, negate), p.expectSymbol(")")
+	}
+
+	tok := p.next()
+	ops := map[string]func(T) Predicate[T]{"=": Eq, "!=": Ne, "<>": Ne, "<": Lt, "<=": Le, ">": Gt, ">=": Ge}
+	op, ok := ops[tok.text]
+	if tok.kind != tokenSymbol || !ok {
+		return nil, p.errorf(tok, "expected comparison operator")
+	}
+	v, err := p.literal()
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		// the comparisons with NULL are false, use IS NULL to test it
+		return func(e T) bool {
+			return false
+		}, nil
+	}
+	return test(op(v), false), nil
+}
+
+// literal parses a number, a string, TRUE, FALSE or NULL
+func (p *parser) literal() (T, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokenString:
+		return tok.text, nil
+	case tok.kind == tokenNumber && strings.Contains(tok.text, "."):
+		if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			return f, nil
+		}
+	case tok.kind == tokenNumber:
+		if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return n, nil
+		}
+	case tok.kind == tokenIdent:
+		switch strings.ToUpper(tok.text) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		case "NULL":
+			return nil, nil
+		}
+	}
+	return nil, p.errorf(tok, "expected value")
+}
+
+// field parses a field path, which is recorded to be checked
+func (p *parser) field() (string, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent || keywords[strings.ToUpper(tok.text)] {
+		return "", p.errorf(tok, "expected field")
+	}
+	for _, name := range strings.Split(tok.text, ".") {
+		if name == "" {
+			return "", fmt.Errorf("stream: query: invalid field %s at %d", tok.text, tok.pos)
+		}
+	}
+	p.query.paths = append(p.query.paths, tok.text)
+	return tok.text, nil
+}
+
+// count parses a non-negative integer
+func (p *parser) count() (int, error) {
+	tok := p.next()
+	n, err := strconv.Atoi(tok.text)
+	if tok.kind != tokenNumber || err != nil || n < 0 {
+		return 0, p.errorf(tok, "expected non-negative integer")
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+// keyword consumes the next token if it is the keyword
+func (p *parser) keyword(word string) bool {
+	if tok := p.peek(); tok.kind == tokenIdent && strings.EqualFold(tok.text, word) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// symbol consumes the next token if it is the symbol
+func (p *parser) symbol(s string) bool {
+	if tok := p.peek(); tok.kind == tokenSymbol && tok.text == s {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(word string) error {
+	if !p.keyword(word) {
+		return p.errorf(p.peek(), "expected %s", word)
+	}
+	return nil
+}
+
+func (p *parser) expectSymbol(s string) error {
+	if !p.symbol(s) {
+		return p.errorf(p.peek(), "expected %s", s)
+	}
+	return nil
+}
+
+// errorf returns the error at tok
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if !strings.HasPrefix(msg, "unexpected") {
+		msg += ", found " + tok.String()
+	}
+	return fmt.Errorf("stream: query: %s at %d", msg, tok.pos)
+}
@@ -0,0 +1,79 @@
+package stream
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tokens, err := lex("select Position.City,COUNT(*) where Name <> 'it''s' and Age>=-1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := make([]string, 0)
+	for _, tok := range tokens {
+		texts = append(texts, tok.text)
+	}
+	want := []string{"select", "Position.City", ",", "COUNT", "(", "*", ")", "where", "Name", "<>", "it's", "and", "Age", ">=", "-1.5", ""}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("lex() = %q", texts)
+	}
+	if _, err := lex("SELECT Id WHERE Name = 'x"); err == nil || err.Error() != "stream: query: unterminated string at 23" {
+		t.Errorf("lex() error = %v", err)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	q, err := parseQuery("SELECT Id, COUNT(*) AS n, SUM(Age) GROUP BY Id ORDER BY n DESC, Id LIMIT 3 OFFSET 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []queryColumn{{name: "Id", path: "Id"}, {name: "n", agg: "COUNT"}, {name: "SUM(Age)", path: "Age", agg: "SUM"}}
+	if !reflect.DeepEqual(q.columns, want) || !reflect.DeepEqual(q.groupBy, []string{"Id"}) {
+		t.Errorf("parseQuery() columns = %v, group by %v", q.columns, q.groupBy)
+	}
+	if !reflect.DeepEqual(q.orderBy, []queryOrder{{name: "n", desc: true}, {name: "Id"}}) || q.limit != 3 || q.offset != 1 {
+		t.Errorf("parseQuery() order by %v, limit %v, offset %v", q.orderBy, q.limit, q.offset)
+	}
+
+	// AND has higher precedence than OR
+	q, err = parseQuery("select * where A = 1 or A = 2 and not B is null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		e    map[string]interface{}
+		want bool
+	}{
+		{map[string]interface{}{"A": 1}, true},
+		{map[string]interface{}{"A": 2}, false},
+		{map[string]interface{}{"A": 2, "B": "b"}, true},
+	} {
+		if got := q.where(tt.e); got != tt.want {
+			t.Errorf("where(%v) = %v, want %v", tt.e, got, tt.want)
+		}
+	}
+}
+
+func TestParseQueryError(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "stream: query: expected SELECT, found end of query at 0"},
+		{"SELECT", "stream: query: expected field, found end of query at 6"},
+		{"SELECT Id WHERE", "stream: query: expected field, found end of query at 15"},
+		{"SELECT Id WHERE Age ~ 1", "stream: query: unexpected character '~' at 20"},
+		{"SELECT Id WHERE Age = Name", "stream: query: expected value, found Name at 22"},
+		{"SELECT Id WHERE Age IN (1, 2", "stream: query: expected ), found end of query at 28"},
+		{"SELECT Id LIMIT -1", "stream: query: expected non-negative integer, found -1 at 16"},
+		{"SELECT Id, Id", "stream: query: duplicate column Id"},
+		{"SELECT Id FROM employees", "stream: query: unexpected FROM at 10"},
+		{"SELECT Position..City", "stream: query: invalid field Position..City at 7"},
+	}
+	for _, tt := range tests {
+		if _, err := parseQuery(tt.query); err == nil || err.Error() != tt.want {
+			t.Errorf("parseQuery(%q) error = %v, want %v", tt.query, err, tt.want)
+		}
+	}
+}
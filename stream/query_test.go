@@ -0,0 +1,195 @@
+package stream
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	people := FromValues(
+		employee{Id: 3, Age: ptr(40), City: &city{Name: ptr("Paris")}},
+		employee{Id: 1, Age: ptr(20), City: &city{Name: ptr("Rome")}},
+		employee{Id: 4, City: &city{Name: ptr("Paris")}},
+		employee{Id: 2, Age: ptr(35)},
+		employee{Id: 5, Age: ptr(25), City: &city{Name: ptr("Paris")}},
+	)
+	run := func(query string) []map[string]interface{} {
+		rows, err := Query(people, query)
+		if err != nil {
+			t.Fatalf("Query(%q) error = %v", query, err)
+		}
+		return rows.ToSlice()
+	}
+
+	got := run("SELECT Id, City.Name AS city WHERE Age > 22 AND City.Name = 'Paris' ORDER BY Id DESC")
+	want := []map[string]interface{}{{"Id": int64(5), "city": "Paris"}, {"Id": int64(3), "city": "Paris"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() = %v", got)
+	}
+	// NULL is the greatest, IS NULL and NOT IN test it
+	got = run("select Id where City.Name is null or Age is null or Age not in (20, 40) order by Age limit 2 offset 1")
+	if !reflect.DeepEqual(got, []map[string]interface{}{{"Id": int64(2)}, {"Id": int64(4)}}) {
+		t.Errorf("Query() = %v", got)
+	}
+	// the comparisons with NULL are false, even if they are negated
+	for _, query := range []string{"SELECT Id WHERE Age != 22", "SELECT Id WHERE Age <> 22", "SELECT Id WHERE Age NOT IN (22)"} {
+		got = run(query + " ORDER BY Id")
+		want := []map[string]interface{}{{"Id": int64(1)}, {"Id": int64(2)}, {"Id": int64(3)}, {"Id": int64(5)}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Query(%q) = %v", query, got)
+		}
+	}
+	if got = run("SELECT Id WHERE Age != NULL OR Age = NULL"); len(got) != 0 {
+		t.Errorf("Query(NULL) = %v", got)
+	}
+	got = run("SELECT * WHERE Id = 1")
+	if len(got) != 1 || got[0]["Age"] != 20 || len(got[0]) != 3 {
+		t.Errorf("Query(*) = %v", got)
+	}
+
+	got = run("SELECT City.Name, COUNT(*), COUNT(Age) AS ages, SUM(Age), AVG(Age) GROUP BY City.Name ORDER BY ages DESC")
+	want = []map[string]interface{}{
+		{"City.Name": "Paris", "COUNT(*)": 3, "ages": 2, "SUM(Age)": int64(65), "AVG(Age)": 32.5},
+		{"City.Name": "Rome", "COUNT(*)": 1, "ages": 1, "SUM(Age)": int64(20), "AVG(Age)": 20.0},
+		{"City.Name": nil, "COUNT(*)": 1, "ages": 1, "SUM(Age)": int64(35), "AVG(Age)": 35.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Query(GROUP BY) = %v", got)
+	}
+	// the structs are grouped by their values, though the pointers in them are different
+	got = run("SELECT City, COUNT(*) AS n GROUP BY City")
+	counts := make(map[string]int)
+	for _, row := range got {
+		name := "NULL"
+		if c, ok := row["City"].(city); ok {
+			name = *c.Name
+		}
+		counts[name] = row["n"].(int)
+	}
+	if !reflect.DeepEqual(counts, map[string]int{"Paris": 3, "Rome": 1, "NULL": 1}) || len(got) != 3 {
+		t.Errorf("Query(GROUP BY struct) = %v", got)
+	}
+	// the aggregate functions without GROUP BY produce a row even if no element
+	got = run("SELECT COUNT(*), SUM(Age), AVG(Age) WHERE Id > 10")
+	if !reflect.DeepEqual(got, []map[string]interface{}{{"COUNT(*)": 0, "SUM(Age)": int64(0), "AVG(Age)": nil}}) {
+		t.Errorf("Query(COUNT) = %v", got)
+	}
+
+	// the query is compiled into ordinary operations, which are optimized
+	rows, _ := Query(people, "SELECT Id ORDER BY Age LIMIT 2")
+	if plan := rows.Explain(); !strings.Contains(plan, "TopK") {
+		t.Errorf("Explain() = %v", plan)
+	}
+}
+
+func TestQueryElements(t *testing.T) {
+	people := FromValues(
+		&employee{Id: 3, Age: ptr(40)},
+		&employee{Id: 1, Age: ptr(20)},
+		nil,
+		&employee{Id: 2, Age: ptr(35)},
+	)
+	s, err := QueryElements(people, "SELECT * WHERE Age >= 35 ORDER BY Id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.ToSlice(); len(got) != 2 || got[0].Id != 2 || got[1].Id != 3 {
+		t.Errorf("QueryElements() = %v", got)
+	}
+	if _, err := QueryElements(people, "SELECT Id"); err == nil {
+		t.Error("QueryElements() should require SELECT *")
+	}
+
+	// the maps are queried without checking
+	records := FromValues(map[string]interface{}{"n": 1.5}, map[string]interface{}{"n": 3})
+	s2, err := QueryElements(records, "SELECT * WHERE n > 2 OR missing = 1")
+	if err != nil || s2.Count() != 1 {
+		t.Errorf("QueryElements() of maps = %v, %v", s2, err)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	people := FromValues[*employee]()
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT Agee", "stream: query: unknown field Agee: stream.employee has no exported field Agee"},
+		{"SELECT Id WHERE City.Zip = 1", "stream: query: unknown field City.Zip: stream.city has no exported field Zip"},
+		{"SELECT Id ORDER BY Name", "stream: query: unknown field Name: stream.employee has no exported field Name"},
+		{"SELECT Id, COUNT(*)", "stream: query: column Id must be in GROUP BY or an aggregate function"},
+		{"SELECT COUNT(*) GROUP BY Age ORDER BY Age", "stream: query: ORDER BY Age is not a column of the grouped rows"},
+		{"SELECT * GROUP BY Age", "stream: query: SELECT * can't be grouped"},
+	}
+	for _, tt := range tests {
+		if _, err := Query(people, tt.query); err == nil || err.Error() != tt.want {
+			t.Errorf("Query(%q) error = %v, want %v", tt.query, err, tt.want)
+		}
+	}
+	// the aliases can be ordered
+	if _, err := Query(people, "SELECT Age AS a ORDER BY a"); err != nil {
+		t.Errorf("Query() error = %v", err)
+	}
+}
+
+func TestGroupValue(t *testing.T) {
+	type node struct {
+		Next *node
+		V    int
+	}
+	// cyclic returns a node whose Next points to itself
+	cyclic := func(v int) node {
+		n := &node{V: v}
+		n.Next = n
+		return *n
+	}
+	k := groupKind{}
+	same := [][2]T{
+		{1, 1},
+		{city{Name: ptr("Paris")}, city{Name: ptr("Paris")}},
+		{[]int{1, 2}, []int{1, 2}},
+		{map[string]*int{"a": ptr(1), "b": nil}, map[string]*int{"b": nil, "a": ptr(1)}},
+		{math.NaN(), math.NaN()},
+		{cyclic(1), cyclic(1)},
+	}
+	for _, vs := range same {
+		if k.value(vs[0]) != k.value(vs[1]) {
+			t.Errorf("%v and %v are in different groups", vs[0], vs[1])
+		}
+	}
+	different := [][2]T{
+		{1, "1"},
+		{1, int64(1)},
+		{city{Name: ptr("Paris")}, city{}},
+		{[]string{"a,b"}, []string{"a", "b"}},
+		{nil, "nil"},
+		{cyclic(1), cyclic(2)},
+	}
+	for _, vs := range different {
+		if k.value(vs[0]) == k.value(vs[1]) {
+			t.Errorf("%v and %v are in the same group", vs[0], vs[1])
+		}
+	}
+}
+
+func TestFieldsOf(t *testing.T) {
+	fields := fieldsOf()
+	// the getters are switched when the type of elements changes
+	for _, tt := range []struct {
+		e    interface{}
+		want map[string]interface{}
+	}{
+		{employee{Id: 1, Age: ptr(20)}, map[string]interface{}{"Id": int64(1), "Age": 20, "City": nil}},
+		{&employee{Id: 2, City: &city{Name: ptr("Rome")}}, map[string]interface{}{"Id": int64(2), "Age": nil, "City": city{Name: ptr("Rome")}}},
+		{city{}, map[string]interface{}{"Name": nil}},
+		{map[string]int{"a": 1}, map[string]interface{}{"a": 1}},
+		{(*employee)(nil), map[string]interface{}{}},
+		{employee{Id: 3}, map[string]interface{}{"Id": int64(3), "Age": nil, "City": nil}},
+	} {
+		if got := fields(tt.e); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fieldsOf(%v) = %v, want %v", tt.e, got, tt.want)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package stream
 
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
 const (
 	stageNone        = 0
 	stageStateless   = 1
@@ -8,57 +16,320 @@ const (
 	stageShortcut    = 4
 )
 
+// stageFlagNames is the names of stage flags, which are shown in the execution plan
+var stageFlagNames = [...]string{"none", "stateless", "stateful", "non-shortcut", "shortcut"}
+
+// the signals returned by actions, which tell the stage machine what to do with the product.
+// dropping is not signalled by the product, so nil is a valid element
+const (
+	actionNext = 0 // actionNext passes the product to the next action
+	actionDrop = 1 // actionDrop drops the element
+	actionStop = 2 // actionStop drops the element and stops pulling the upstream
+	actionLast = 3 // actionLast passes the product to the next action as the last one, and stops pulling the upstream
+	// actionExpand means the product is an iterator, whose elements are passed to the next action one by one
+	actionExpand = 4
+	// actionFail means the product is an error, the stage machine stops and reports it as the error of execution
+	actionFail = 5
+)
+
 type stage struct {
-	prepare   func()
-	action    func(T, int) (R, bool)
+	name string // name is the name of operation, which locates the errors
+	// init creates the action and flush with their own state, it is called for each execution,
+	// so an execution never shares state with others. the stateless actions which have state can't be executed in parallel
+	init   func() stage
+	action func(T, int) (R, int)
+	// parallel is the action of stateful or terminate stage when the stream executes parallel,
+	// it receives the products split in chunks and returns the products of stateful stage.
+	// the workers check stopped between elements and stop when the execution failed or was canceled.
+	// the shortcut stage receives the chunks batch by batch, and returns non-nil when it is done,
+	// so the upstream is not pulled any more. action is used if it is nil
+	parallel func(chunks [][]T, workers int, stopped func() bool) []T
+	// flush returns the rest products of stateless stage when upstream has no more element,
+	// which are passed to the next action. nothing is flushed if it or its result is nil
+	flush     func() iterator
 	stageFlag int
+	kind      int         // kind is the kind of operation which the optimizer rewrites
+	param     interface{} // param is the param of operation which the optimizer reads, like the number of Limit or the comparator of Sort
 }
 
+// iterator pulls the elements one by one, ok is false when there is no more element
+type iterator func() (e T, ok bool)
+
 type stageMachine struct {
-	s      *stream
-	stages [][]stage
+	src       iterator
+	stages    [][]stage
+	workers   int    // workers is the number of goroutines, the stages are executed sequential if it is 0
+	unordered bool   // unordered allows the parallel stages produce elements out of encounter order
+	cur       cursor // cur is the action executing in the caller goroutine, which locates the panics
+	ctx       context.Context
+	done      <-chan struct{} // done is closed when ctx is done, it is nil if the execution can't be canceled
+	profile   *profile        // profile records the statistics of stages, it is nil if the stream is not instrumented
+
+	mu     sync.Mutex
+	err    error // err is the first error of execution
+	failed int32 // failed is set to 1 with err, so the workers can stop without locking
+}
+
+// cursor is the action executing and the index of element it receives, index is -1 if it receives all elements
+type cursor struct {
+	stage string
+	index int
+}
+
+// fail records the error of the action at cur, only the first error is kept
+func (m *stageMachine) fail(cur cursor, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return
+	}
+	if se, ok := err.(*StageError); ok && se.Stage == "" {
+		// the action located the element itself
+		se.Stage = cur.stage
+		m.err = se
+	} else {
+		m.err = &StageError{Stage: cur.stage, Index: cur.index, Err: err}
+	}
+	atomic.StoreInt32(&m.failed, 1)
+}
+
+// abort stops the execution with err, which is returned by the terminal operations as it is
+func (m *stageMachine) abort(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+	atomic.StoreInt32(&m.failed, 1)
+}
+
+// stopped reports whether the execution failed or was canceled
+func (m *stageMachine) stopped() bool {
+	if atomic.LoadInt32(&m.failed) == 1 {
+		return true
+	}
+	if m.done == nil {
+		return false
+	}
+	select {
+	case <-m.done:
+		m.abort(m.ctx.Err())
+		return true
+	default:
+		return false
+	}
+}
+
+// never is the stopped of sequential actions, whose elements were checked when they were pulled
+func never() bool {
+	return false
+}
+
+// guard returns an iterator of it, which converts the panics of pulling to the error of execution.
+// it panics with the error when there is no more element, so the error is reported to the downstream machine
+func (m *stageMachine) guard(it iterator) iterator {
+	pull := func() (e T, ok bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				m.fail(m.cur, recovered(r))
+				e, ok = nil, false
+			}
+		}()
+		return it()
+	}
+	return func() (T, bool) {
+		if e, ok := pull(); ok {
+			return e, true
+		}
+		if m.stopped() {
+			panic(m.err)
+		}
+		return nil, false
+	}
+}
+
+// batchSize is the number of elements pulled for each worker at once when the stream executes parallel,
+// so the upstream is pulled in bounded batches and the short-circuiting operations can stop an infinite source
+const batchSize = 1024
+
+// product is the products between stages, which is an iterator or the batches of chunks processed by workers
+type product struct {
+	m  *stageMachine
+	it iterator
+	// batches returns the chunks of next batch, ok is false when there is no more batch
+	batches func() (chunks [][]T, ok bool)
+}
+
+// iterator returns products as iterator, products are pulled lazily if it is an iterator or batches already
+func (p *product) iterator() iterator {
+	if p.batches != nil {
+		it := sliceIterator(nil)
+		next := p.batches
+		p.it = func() (T, bool) {
+			for {
+				if e, ok := it(); ok {
+					return e, true
+				}
+				chunks, ok := next()
+				if !ok {
+					return nil, false
+				}
+				it = sliceIterator(flatten(chunks))
+			}
+		}
+		p.batches = nil
+	}
+	return p.it
+}
+
+// slice returns products as slice
+func (p *product) slice() []T {
+	return drain(p.iterator())
+}
+
+// split returns products split in chunks for workers
+func (p *product) split() [][]T {
+	return p.m.split(p.slice())
+}
+
+// batch returns the next batch of products split in chunks for workers, ok is false when there is no more product.
+// at most batchSize elements for each worker are pulled from an iterator
+func (p *product) batch() (chunks [][]T, ok bool) {
+	if p.batches != nil {
+		return p.batches()
+	}
+	buf := make([]T, 0)
+	for len(buf) < p.m.workers*batchSize {
+		e, ok := p.it()
+		if !ok {
+			break
+		}
+		buf = append(buf, e)
+	}
+	if len(buf) == 0 {
+		return nil, false
+	}
+	return p.m.split(buf), true
 }
 
-func (m *stageMachine) run() {
-	prod := make([]T, len(m.s.data))
-	copy(prod, m.s.data)
+// run executes the stages and returns the products of the last stage.
+// it stops at the first error of stages, which is kept in m.err, and the products are empty
+func (m *stageMachine) run() (prod *product) {
+	n := 0
+	prod = &product{m: m, it: func() (T, bool) {
+		if m.stopped() {
+			return nil, false
+		}
+		m.cur = cursor{stage: "Source", index: n}
+		n++
+		return m.src()
+	}}
+	defer func() {
+		if r := recover(); r != nil {
+			m.fail(m.cur, recovered(r))
+		}
+		if m.stopped() {
+			prod = &product{m: m, it: sliceIterator(nil)}
+		}
+	}()
 
 	for _, s := range m.stages {
+		if m.stopped() {
+			break
+		}
 		switch s[0].stageFlag {
 		case stageNone:
 		case stageStateless:
-			// stateless has multiple actions, which can be connected in series
-			// each action receive an element and return the product
-			ii := 0
-			skip := false
-			for i, e := range prod {
-				for _, ss := range s {
-					e, skip = ss.action(e, i)
-					if e == nil {
-						break
+			// stateless has multiple actions, which can be connected in series,
+			// the elements are pulled from upstream one by one only when downstream needs
+			if m.workers > 0 && parallelizable(s) {
+				// the batches are executed when downstream pulls them, so the upstream is not pulled all at once
+				up, fused, offset := prod, s, 0
+				prod = &product{m: m, batches: func() ([][]T, bool) {
+					chunks, ok := up.batch()
+					if !ok || m.stopped() {
+						return nil, false
+					}
+					ret := m.parallelFuse(chunks, fused, offset)
+					for _, c := range chunks {
+						offset += len(c)
 					}
+					return ret, true
+				}}
+			} else {
+				prod.it = m.fuse(prod.iterator(), s, &m.cur, 0)
+			}
+		case stageStateful:
+			// stateful only has one action, which receives a slice and returns a slice of products
+			var res []T
+			if m.workers > 0 && s[0].parallel != nil {
+				chunks := prod.split()
+				if m.stopped() {
+					break
 				}
-				if e != nil {
-					prod[ii] = e
-					ii++
+				m.cur = cursor{stage: s[0].name, index: -1}
+				res = s[0].parallel(chunks, m.workers, m.stopped)
+			} else {
+				ele := prod.slice()
+				if m.stopped() {
+					break
 				}
-				if skip {
+				m.cur = cursor{stage: s[0].name, index: -1}
+				r, signal := s[0].action(ele, len(ele))
+				if signal == actionFail {
+					m.fail(m.cur, r.(error))
 					break
 				}
+				res = r.([]T)
 			}
-			prod = prod[:ii]
-		case stageStateful:
-			// stateful only has one action, which receives a slice and returns a slice of products
-			res, _ := s[0].action(prod, len(prod))
-			prod = res.([]T)
+			prod = &product{m: m, it: sliceIterator(res)}
 		case stageNonShortcut:
 			// non-shortcut only has one action, which receives a slice and returns a slice of products
-			s[0].action(prod, len(prod))
+			if m.workers > 0 && s[0].parallel != nil {
+				chunks := prod.split()
+				if m.stopped() {
+					break
+				}
+				m.cur = cursor{stage: s[0].name, index: -1}
+				s[0].parallel(chunks, m.workers, m.stopped)
+			} else {
+				ele := prod.slice()
+				if m.stopped() {
+					break
+				}
+				m.cur = cursor{stage: s[0].name, index: -1}
+				if r, signal := s[0].action(ele, len(ele)); signal == actionFail {
+					m.fail(m.cur, r.(error))
+				}
+			}
 		case stageShortcut:
-			// shortcut only has one action, which receives an element and consumes it and will break when can be done
-			for i, e := range prod {
-				_, skip := s[0].action(e, i)
-				if skip {
+			// shortcut only has one action, which receives an element and consumes it and will break when can be done,
+			// so the upstream will not be pulled any more
+			if m.workers > 0 && s[0].parallel != nil {
+				for {
+					chunks, ok := prod.batch()
+					if !ok || m.stopped() {
+						break
+					}
+					m.cur = cursor{stage: s[0].name, index: -1}
+					if s[0].parallel(chunks, m.workers, m.stopped) != nil {
+						break
+					}
+				}
+				break
+			}
+			it := prod.iterator()
+			for i := 0; ; i++ {
+				e, ok := it()
+				if !ok {
+					break
+				}
+				m.cur = cursor{stage: s[0].name, index: i}
+				r, signal := s[0].action(e, i)
+				if signal == actionFail {
+					m.fail(m.cur, r.(error))
+				}
+				if signal == actionStop || signal == actionFail {
 					break
 				}
 			}
@@ -66,3 +337,464 @@ func (m *stageMachine) run() {
 	}
 	return
 }
+
+// group groups the stages of an execution, the adjacent stateless stages are fused in one group,
+// each of the others is a group alone. if parallel is true, the stateless stages which have state are split from
+// the others, so the others are still executed in parallel
+func group(opts []stage, parallel bool) [][]stage {
+	stages := make([][]stage, 0)
+	for _, sg := range opts {
+		if sLen := len(stages); sLen > 0 {
+			last := stages[sLen-1]
+			if last[0].stageFlag == sg.stageFlag && sg.stageFlag == stageStateless &&
+				(!parallel || (last[0].init == nil) == (sg.init == nil)) {
+				// only stateless actions can be group
+				stages[sLen-1] = append(last, sg)
+			} else {
+				stages = append(stages, make([]stage, 1))
+				stages[sLen][0] = sg
+			}
+		} else {
+			stages = append(stages, make([]stage, 1))
+			stages[0][0] = sg
+		}
+	}
+	return stages
+}
+
+// split splits the elements in chunks, there are more chunks than workers to balance the load
+func (m *stageMachine) split(prod []T) [][]T {
+	n := m.workers * 4
+	if n > len(prod) {
+		n = len(prod)
+	}
+	chunks := make([][]T, 0, n)
+	for i := 0; i < n; i++ {
+		chunks = append(chunks, prod[i*len(prod)/n:(i+1)*len(prod)/n])
+	}
+	return chunks
+}
+
+// parallelFuse executes the fused stateless actions on each chunk by workers, offset is the index of the first element.
+// the products keep the encounter order unless the stream is unordered,
+// which chunks are appended in the order they are completed
+func (m *stageMachine) parallelFuse(chunks [][]T, s []stage, offset int) [][]T {
+	ret := make([][]T, len(chunks))
+	if m.unordered {
+		ret = ret[:0]
+	}
+	offsets := make([]int, len(chunks))
+	offsets[0] = offset
+	for i := 1; i < len(chunks); i++ {
+		offsets[i] = offsets[i-1] + len(chunks[i-1])
+	}
+	mu := sync.Mutex{}
+	runWorkers(m.workers, len(chunks), func(i int) {
+		if m.stopped() {
+			return
+		}
+		// each worker has its own cursor to locate the panics
+		cur := cursor{}
+		defer func() {
+			if r := recover(); r != nil {
+				m.fail(cur, recovered(r))
+			}
+		}()
+		res := drain(m.fuse(sliceIterator(chunks[i]), s, &cur, offsets[i]))
+		if !m.unordered {
+			ret[i] = res
+			return
+		}
+		mu.Lock()
+		ret = append(ret, res)
+		mu.Unlock()
+	})
+	return ret
+}
+
+// parallelizable reports whether the stateless actions can be executed in parallel
+func parallelizable(s []stage) bool {
+	for _, ss := range s {
+		if ss.init != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// runWorkers calls f(0) to f(n-1) with workers goroutines and waits them done.
+// if f panics, the rest calls are skipped and the first panic is raised again in the caller goroutine
+func runWorkers(workers int, n int, f func(i int)) {
+	jobs := make(chan int, n)
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	var panicked interface{}
+	stop := int32(0)
+	once := sync.Once{}
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					once.Do(func() {
+						panicked = r
+					})
+					atomic.StoreInt32(&stop, 1)
+				}
+			}()
+			for i := range jobs {
+				if atomic.LoadInt32(&stop) == 1 {
+					return
+				}
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+	if panicked != nil {
+		panic(panicked)
+	}
+}
+
+// fuse connects the stateless actions in series,
+// each action receive an element and return the product with the signal.
+// the expanded iterators are pulled before upstream, so an element is expanded only when downstream needs.
+// when there is no more element, the actions are flushed in order.
+// cur is updated before each action is called, offset is the index of the first element of up
+func (m *stageMachine) fuse(up iterator, s []stage, cur *cursor, offset int) iterator {
+	type pending struct {
+		it    iterator // it is the upstream, an expanded or a flushed iterator
+		next  int      // next is the index of action which the elements of it are passed to
+		index int      // index is the index of element which is expanded to it, -1 if it is flushed
+	}
+	stack := []pending{{it: up, next: 0}}
+	counts := make([]int, len(s)) // counts is the index of element received by each action
+	counts[0] = offset
+	flushed := 0 // flushed is the index of next action to flush, the actions before it receive no more element
+
+	return func() (T, bool) {
+		for {
+			if m.stopped() {
+				// the execution failed here or in other workers
+				return nil, false
+			}
+			if len(stack) == 0 {
+				if flushed >= len(s) {
+					return nil, false
+				}
+				if f := s[flushed].flush; f != nil {
+					*cur = cursor{stage: s[flushed].name, index: -1}
+					if it := f(); it != nil {
+						stack = append(stack, pending{it: it, next: flushed + 1, index: -1})
+					}
+				}
+				flushed++
+				continue
+			}
+
+			top := stack[len(stack)-1]
+			if top.next > 0 {
+				// the panics of pulling the expanded or flushed iterator belong to the action produces it
+				*cur = cursor{stage: s[top.next-1].name, index: top.index}
+			}
+			e, ok := top.it()
+			if !ok {
+				stack = stack[:len(stack)-1]
+				continue
+			}
+			next := true
+			for i := top.next; i < len(s) && next; i++ {
+				var signal int
+				*cur = cursor{stage: s[i].name, index: counts[i]}
+				e, signal = s[i].action(e, counts[i])
+				counts[i]++
+				switch signal {
+				case actionFail:
+					m.fail(*cur, e.(error))
+					stack, next, flushed = stack[:0], false, len(s)
+				case actionDrop:
+					next = false
+				case actionStop:
+					// all pending iterators are upstream of the action
+					stack, next, flushed = stack[:0], false, i
+				case actionLast:
+					stack, flushed = stack[:0], i
+				case actionExpand:
+					stack, next = append(stack, pending{it: e.(iterator), next: i + 1, index: counts[i] - 1}), false
+				}
+			}
+			if next {
+				return e, true
+			}
+		}
+	}
+}
+
+// limitStage limits elements
+func limitStage(limit int) stage {
+	return stage{
+		name: "Limit",
+		init: func() stage {
+			count := 0
+			return stage{action: func(ele T, i int) (R, int) {
+				if count >= limit {
+					return nil, actionStop
+				}
+				count++
+				if count >= limit {
+					// stop pulling the source as soon as the limit reached
+					return ele, actionLast
+				}
+				return ele, actionNext
+			}}
+		},
+		stageFlag: stageStateless,
+		kind:      kindLimit,
+		param:     limit,
+	}
+}
+
+// skipStage skips elements
+func skipStage(num int) stage {
+	return stage{
+		name: "Skip",
+		init: func() stage {
+			count := 0
+			return stage{action: func(ele T, i int) (R, int) {
+				if count < num {
+					count++
+					return nil, actionDrop
+				}
+				return ele, actionNext
+			}}
+		},
+		stageFlag: stageStateless,
+		kind:      kindSkip,
+		param:     num,
+	}
+}
+
+// zipStage combines each element with the element of other at the same position by f,
+// it stops when other has no more element. if all is true, the shorter one is padded by padLeft or padRight
+// until both have no more element
+func zipStage(other func() iterator, f func(T, T) T, all bool, padLeft T, padRight T) stage {
+	name := "Zip"
+	if all {
+		name = "ZipAll"
+	}
+	return stage{
+		name: name,
+		init: func() stage {
+			it := other()
+			return stage{
+				action: func(ele T, i int) (R, int) {
+					o, ok := it()
+					if !ok {
+						if !all {
+							return nil, actionStop
+						}
+						o = padRight
+					}
+					return f(ele, o), actionNext
+				},
+				flush: func() iterator {
+					if !all {
+						return nil
+					}
+					return func() (T, bool) {
+						o, ok := it()
+						if !ok {
+							return nil, false
+						}
+						return f(padLeft, o), true
+					}
+				},
+			}
+		},
+		stageFlag: stageStateless,
+	}
+}
+
+// interleaveStage passes each element and the element of other in turn, then the rest elements of other
+func interleaveStage(other func() iterator) stage {
+	return stage{
+		name: "Interleave",
+		init: func() stage {
+			it := other()
+			return stage{
+				action: func(ele T, i int) (R, int) {
+					o, ok := it()
+					if !ok {
+						return ele, actionNext
+					}
+					return sliceIterator([]T{ele, o}), actionExpand
+				},
+				flush: func() iterator {
+					return it
+				},
+			}
+		},
+		stageFlag: stageStateless,
+	}
+}
+
+// windowStage produces the windows of size as []E, which start every step elements.
+// the windows smaller than size are flushed at the end if partial is true
+func windowStage[E any](size int, step int, partial bool) stage {
+	if size <= 0 || step <= 0 {
+		panic("size and step of window must be positive")
+	}
+	return stage{
+		name: "Windowed",
+		init: func() stage {
+			buf := make([]E, 0, size) // buf is the elements from start of next window
+			start, count := 0, 0
+			window := func() []E {
+				ret := make([]E, len(buf))
+				copy(ret, buf)
+				// move to next window
+				start += step
+				if step < len(buf) {
+					buf = buf[step:]
+				} else {
+					buf = buf[:0]
+				}
+				return ret
+			}
+			return stage{
+				action: func(ele T, i int) (R, int) {
+					count++
+					if count <= start {
+						// the element is between windows
+						return nil, actionDrop
+					}
+					buf = append(buf, as[E](ele))
+					if len(buf) < size {
+						return nil, actionDrop
+					}
+					return window(), actionNext
+				},
+				flush: func() iterator {
+					if !partial {
+						return nil
+					}
+					return func() (T, bool) {
+						if start >= count {
+							return nil, false
+						}
+						return window(), true
+					}
+				},
+			}
+		},
+		stageFlag: stageStateless,
+	}
+}
+
+// drain pulls all elements of the iterator into a new slice
+func drain(it iterator) []T {
+	prod := make([]T, 0)
+	for e, ok := it(); ok; e, ok = it() {
+		prod = append(prod, e)
+	}
+	return prod
+}
+
+// sliceIterator iterates the slice
+func sliceIterator(data []T) iterator {
+	i := 0
+	return func() (T, bool) {
+		if i >= len(data) {
+			return nil, false
+		}
+		i++
+		return data[i-1], true
+	}
+}
+
+// typedSliceIterator iterates the slice of E
+func typedSliceIterator[E any](data []E) iterator {
+	i := 0
+	return func() (T, bool) {
+		if i >= len(data) {
+			return nil, false
+		}
+		i++
+		return data[i-1], true
+	}
+}
+
+// byteIterator iterates the bytes of string
+func byteIterator(s string) iterator {
+	i := 0
+	return func() (T, bool) {
+		if i >= len(s) {
+			return nil, false
+		}
+		i++
+		return s[i-1], true
+	}
+}
+
+// runeIterator iterates the code points of string
+func runeIterator(s string) iterator {
+	i := 0
+	return func() (T, bool) {
+		if i >= len(s) {
+			return nil, false
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		return r, true
+	}
+}
+
+// reflectIterator iterates the slice, array or string by reflection
+func reflectIterator(v reflect.Value) iterator {
+	i := 0
+	return func() (T, bool) {
+		if i >= v.Len() {
+			return nil, false
+		}
+		i++
+		return v.Index(i - 1).Interface(), true
+	}
+}
+
+// concatIterator iterates the iterators created by srcs one after another,
+// each iterator is created when the previous one has no more element
+func concatIterator(srcs ...func() iterator) iterator {
+	var it iterator
+	return func() (T, bool) {
+		for {
+			if it == nil {
+				if len(srcs) == 0 {
+					return nil, false
+				}
+				it, srcs = srcs[0](), srcs[1:]
+			}
+			if e, ok := it(); ok {
+				return e, true
+			}
+			it = nil
+		}
+	}
+}
+
+// flatten joins the chunks in one slice
+func flatten(chunks [][]T) []T {
+	n := 0
+	for _, c := range chunks {
+		n += len(c)
+	}
+	ret := make([]T, 0, n)
+	for _, c := range chunks {
+		ret = append(ret, c...)
+	}
+	return ret
+}
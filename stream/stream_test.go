@@ -1 +1,670 @@
 package stream
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestUntyped(t *testing.T) {
+	s := Of(1, 2, 3, 4, 5, 6, 7, 8).
+		Slice(1, 6).
+		Filter(func(e T) bool {
+			return e.(int)%2 == 0
+		}).
+		Sort(func(left T, right T) int {
+			return right.(int) - left.(int)
+		})
+	if got := s.Join(","); got != "6,4,2" {
+		t.Errorf("Join() = %v, want 6,4,2", got)
+	}
+	sum := s.Reduce(func(acc R, e T, idx int, sLen int) R {
+		return acc.(int) + e.(int)
+	}, 0)
+	if sum != 12 {
+		t.Errorf("Reduce() = %v, want 12", sum)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	s := FromValues(1, 2, 3, 4, 5).
+		Filter(func(e int) bool {
+			return e%2 == 1
+		})
+	strs := Map(s, func(e int) string {
+		return strconv.Itoa(e * 10)
+	}).ToSlice()
+	if !reflect.DeepEqual(strs, []string{"10", "30", "50"}) {
+		t.Errorf("ToSlice() = %v", strs)
+	}
+	sum := s.Reduce(func(acc int, e int, idx int, sLen int) int {
+		return acc + e
+	}, 0)
+	if sum != 9 {
+		t.Errorf("Reduce() = %v, want 9", sum)
+	}
+	if first := s.FindFirst(func(e int) bool { return e > 1 }).Get(); first != 3 {
+		t.Errorf("FindFirst() = %v, want 3", first)
+	}
+}
+
+func TestTypedUntyped(t *testing.T) {
+	typed := Typed[int](Of(3, 1, 2)).Sort(func(left int, right int) int {
+		return left - right
+	})
+	if got := typed.ToSlice(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Typed().ToSlice() = %v", got)
+	}
+	untyped := Untyped(typed).Map(func(e T) R {
+		return e.(int) * 2
+	})
+	if got := untyped.ToSlice(); !reflect.DeepEqual(got, []T{2, 4, 6}) {
+		t.Errorf("Untyped().ToSlice() = %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Typed() should panic on a wrong element type")
+		}
+	}()
+	Typed[string](Of(1)).ToSlice()
+}
+
+func TestGenerate(t *testing.T) {
+	calls := 0
+	s := Generate(func() int {
+		calls++
+		return calls
+	}).Limit(5)
+	if got := s.ToSlice(); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+	if calls != 5 {
+		t.Errorf("supplier called %v times, want 5", calls)
+	}
+	if got := s.Count(); got != 5 {
+		t.Errorf("Count() = %v, want 5", got)
+	}
+
+	// the supplier is not called if no element passes the limit
+	calls = 0
+	gen := Generate(func() int {
+		calls++
+		return calls
+	})
+	for _, s := range []Stream[int]{
+		gen.Limit(0),
+		gen.Map(func(e int) int { return e * 2 }).Limit(-1).Optimize(false),
+		gen.Parallel(4).Sort(NaturalOrder[int]()).Limit(0),
+		gen.Skip(3).Limit(2).Skip(2),
+	} {
+		if got := s.ToSlice(); len(got) != 0 || calls != 0 {
+			t.Errorf("ToSlice() = %v, supplier called %v times", got, calls)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	pow := Iterate(1, func(e int) int {
+		return e * 2
+	})
+	if got := pow.FindFirst(func(e int) bool { return e > 1000 }).Get(); got != 1024 {
+		t.Errorf("FindFirst() = %v, want 1024", got)
+	}
+	if !pow.AnyMatch(func(e int) bool { return e == 64 }) {
+		t.Error("AnyMatch() = false, want true")
+	}
+	if pow.AllMatch(func(e int) bool { return e < 100 }) {
+		t.Error("AllMatch() = true, want false")
+	}
+	got := pow.Filter(func(e int) bool {
+		return e > 10
+	}).Limit(3).ToSlice()
+	if !reflect.DeepEqual(got, []int{16, 32, 64}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+
+	// f is applied lazily, so it is never applied to the last element
+	calls := 0
+	got = Iterate(1, func(e int) int {
+		calls++
+		if e >= 8 {
+			panic("f applied to the last element")
+		}
+		return e * 2
+	}).Limit(4).ToSlice()
+	if !reflect.DeepEqual(got, []int{1, 2, 4, 8}) || calls != 3 {
+		t.Errorf("ToSlice() = %v, f called %v times, want 3", got, calls)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	if got := Cycle("a", "b", "c").Limit(7).Join(""); got != "abcabca" {
+		t.Errorf("Join() = %v, want abcabca", got)
+	}
+	if got := Cycle[int]().Count(); got != 0 {
+		t.Errorf("Count() = %v, want 0", got)
+	}
+	if got := Repeat("ab", 3).Join("-"); got != "ab-ab-ab" {
+		t.Errorf("Join() = %v, want ab-ab-ab", got)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	data := make([]int, 10000)
+	for i := range data {
+		data[i] = (i * 7919) % 10007
+	}
+	pipeline := func(s Stream[int]) Stream[int] {
+		return s.
+			Filter(func(e int) bool {
+				return e%3 != 0
+			}).
+			Map(func(e int) int {
+				return e * 2
+			})
+	}
+	seq := pipeline(FromSlice(data))
+	par := pipeline(FromSlice(data).Parallel(4))
+
+	if got, want := par.ToSlice(), seq.ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Error("parallel ToSlice() is not equal to sequential")
+	}
+	if got, want := par.Count(), seq.Count(); got != want {
+		t.Errorf("parallel Count() = %v, want %v", got, want)
+	}
+	sum := func(acc int, e int, idx int, sLen int) int {
+		return acc + e
+	}
+	if got, want := par.Reduce(sum, 0), seq.Reduce(sum, 0); got != want {
+		t.Errorf("parallel Reduce() = %v, want %v", got, want)
+	}
+	asc := func(left int, right int) int {
+		return left - right
+	}
+	if got, want := par.Sort(asc).ToSlice(), seq.Sort(asc).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Error("parallel Sort() is not equal to sequential")
+	}
+	if got, want := par.Sort(asc).Limit(10).ToSlice(), seq.Sort(asc).Limit(10).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Errorf("parallel Sort().Limit() = %v, want %v", got, want)
+	}
+	if got, want := par.Sequential().Unordered().ToSlice(), seq.ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Error("sequential unordered ToSlice() is not equal to ordered")
+	}
+
+	unordered := par.Unordered().ToSlice()
+	if len(unordered) != seq.Count() {
+		t.Errorf("unordered ToSlice() has %v elements, want %v", len(unordered), seq.Count())
+	}
+	if got, want := FromSlice(unordered).Sort(asc).ToSlice(), seq.Sort(asc).ToSlice(); !reflect.DeepEqual(got, want) {
+		t.Error("unordered ToSlice() has different elements with ordered")
+	}
+}
+
+func TestParallelInfinite(t *testing.T) {
+	got := Iterate(1, func(e int) int {
+		return e + 1
+	}).Parallel(4).Map(func(e int) int {
+		return e * e
+	}).Limit(4).ToSlice()
+	if !reflect.DeepEqual(got, []int{1, 4, 9, 16}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+
+	// the parallel stages pull the source in batches, so the short-circuiting operations stop it
+	pulled := 0
+	found := Iterate(0, func(e int) int {
+		pulled++
+		return e + 1
+	}).Parallel(2).Filter(func(e int) bool {
+		return e%7 == 6
+	}).AnyMatch(func(e int) bool {
+		return e > 100
+	})
+	if !found || pulled >= 2*batchSize {
+		t.Errorf("AnyMatch() = %v after %v elements pulled", found, pulled)
+	}
+}
+
+func TestParallelAfterSkip(t *testing.T) {
+	// Map waits until another element is mapped at the same time, so it is slow if Map runs sequential
+	mapping, overlapped := int32(0), int32(0)
+	got := FromValues(0, 1, 2, 3, 4, 5, 6, 7, 8).Parallel(4).Skip(1).Map(func(e int) int {
+		if atomic.AddInt32(&mapping, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		for i := 0; i < 100 && atomic.LoadInt32(&overlapped) == 0; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		atomic.AddInt32(&mapping, -1)
+		return e * 2
+	}).ToSlice()
+	if !reflect.DeepEqual(got, []int{2, 4, 6, 8, 10, 12, 14, 16}) || atomic.LoadInt32(&overlapped) == 0 {
+		t.Errorf("ToSlice() = %v, mapped in parallel %v", got, overlapped == 1)
+	}
+}
+
+func TestNilElements(t *testing.T) {
+	type employee struct {
+		Phone *string
+	}
+	phone := "10086"
+	employees := FromValues(&employee{Phone: &phone}, &employee{}, nil)
+	phones := Map(employees, func(e *employee) *string {
+		if e == nil {
+			return nil
+		}
+		return e.Phone
+	}).ToSlice()
+	if len(phones) != 3 || phones[0] != &phone || phones[1] != nil || phones[2] != nil {
+		t.Errorf("ToSlice() = %v", phones)
+	}
+
+	untyped := Of(1, nil, 2).Map(func(e T) R {
+		if e == nil {
+			return nil
+		}
+		return e.(int) * 10
+	}).Push(nil)
+	if got := untyped.ToSlice(); !reflect.DeepEqual(got, []T{10, nil, 20, nil}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+	if got := untyped.Count(); got != 4 {
+		t.Errorf("Count() = %v, want 4", got)
+	}
+	if got := Of(1, 2, 3).Fill(nil).ToSlice(); !reflect.DeepEqual(got, []T{nil, nil, nil}) {
+		t.Errorf("Fill(nil).ToSlice() = %v", got)
+	}
+	nonNil := untyped.Filter(func(e T) bool {
+		return e != nil
+	})
+	if got := nonNil.ToSlice(); !reflect.DeepEqual(got, []T{10, 20}) {
+		t.Errorf("Filter().ToSlice() = %v", got)
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	words := Of("ab", "", "cde").FlatMap(func(e T) Stream[T] {
+		return OfSlice(e)
+	}).Map(func(e T) R {
+		return string(e.(byte))
+	})
+	if got := words.Join(","); got != "a,b,c,d,e" {
+		t.Errorf("Join() = %v, want a,b,c,d,e", got)
+	}
+
+	calls := 0
+	got := FlatMap(FromValues(1, 2, 3, 4), func(e int) Stream[string] {
+		calls++
+		return Cycle(strconv.Itoa(e)) // the inner stream is infinite
+	}).Limit(3).ToSlice()
+	if !reflect.DeepEqual(got, []string{"1", "1", "1"}) || calls != 1 {
+		t.Errorf("ToSlice() = %v with %v inner streams", got, calls)
+	}
+
+	got = FlatMapSlice(FromValues(1, 2, 3), func(e int) []string {
+		return []string{strconv.Itoa(e), strconv.Itoa(-e)}
+	}).Filter(func(e string) bool {
+		return e != "-2"
+	}).ToSlice()
+	if !reflect.DeepEqual(got, []string{"1", "-1", "2", "3", "-3"}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+
+	nested := FromValues(1, 2).FlatMap(func(e int) Stream[int] {
+		return FromValues(e, e*10).FlatMapSlice(func(e int) []int {
+			return []int{e, e + 1}
+		})
+	})
+	if got := nested.ToSlice(); !reflect.DeepEqual(got, []int{1, 2, 10, 11, 2, 3, 20, 21}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+	if got := nested.Parallel(3).ToSlice(); !reflect.DeepEqual(got, []int{1, 2, 10, 11, 2, 3, 20, 21}) {
+		t.Errorf("parallel ToSlice() = %v", got)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	if got := Flatten(FromValues([]int{1, 2}, nil, []int{3})).ToSlice(); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("Flatten() = %v", got)
+	}
+	got := Of([]int{1, 2}, [2]string{"a", "b"}, []T{nil}, 3).Flatten().ToSlice()
+	if !reflect.DeepEqual(got, []T{1, 2, "a", "b", nil, 3}) {
+		t.Errorf("Flatten() = %v", got)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	asc := func(left int, right int) int {
+		return left - right
+	}
+	s := FromValues(3, 1, 4, 1, 5, 9, 2, 6)
+	if got, ok := s.Min(asc).Value(); got != 1 || !ok {
+		t.Errorf("Min() = %v, %v", got, ok)
+	}
+	if got, ok := s.Max(asc).Value(); got != 9 || !ok {
+		t.Errorf("Max() = %v, %v", got, ok)
+	}
+	if got, ok := s.Parallel(3).Max(asc).Value(); got != 9 || !ok {
+		t.Errorf("parallel Max() = %v, %v", got, ok)
+	}
+	if _, ok := FromValues[int]().Min(asc).Value(); ok {
+		t.Error("Min() of empty stream should not be found")
+	}
+
+	calls := 0
+	length := func(e string) int {
+		calls++
+		return len(e)
+	}
+	words := FromValues("bb", "a", "ccc", "dd", "eee")
+	if got, ok := MaxBy(words, length).Value(); got != "ccc" || !ok {
+		t.Errorf("MaxBy() = %v, %v", got, ok)
+	}
+	if calls != 5 {
+		t.Errorf("key computed %v times, want 5", calls)
+	}
+	strlen := func(e string) int {
+		return len(e)
+	}
+	if got, ok := MinBy(words.Parallel(2), strlen).Value(); got != "a" || !ok {
+		t.Errorf("parallel MinBy() = %v, %v", got, ok)
+	}
+	if _, ok := MinBy(FromValues[string](), length).Value(); ok {
+		t.Error("MinBy() of empty stream should not be found")
+	}
+}
+
+func TestFind(t *testing.T) {
+	even := func(e int) bool {
+		return e%2 == 0
+	}
+	s := FromValues(1, 2, 3, 4, 5)
+	if got, ok := s.FindLast(even).Value(); got != 4 || !ok {
+		t.Errorf("FindLast() = %v, %v", got, ok)
+	}
+	if got, ok := s.FindAny(even).Value(); got != 2 || !ok {
+		t.Errorf("FindAny() = %v, %v", got, ok)
+	}
+	if got, ok := s.Parallel(4).FindAny(even).Value(); !ok || got%2 != 0 {
+		t.Errorf("parallel FindAny() = %v, %v", got, ok)
+	}
+	if _, ok := s.Parallel(4).FindAny(func(e int) bool { return e > 5 }).Value(); ok {
+		t.Error("parallel FindAny() should not be found")
+	}
+	// the batches are searched as they are pulled, so an infinite source is stopped once found
+	pulled := 0
+	naturals := Iterate(0, func(e int) int {
+		pulled++
+		return e + 1
+	})
+	if got, ok := naturals.Parallel(2).FindAny(func(e int) bool { return e > 3000 }).Value(); !ok || got <= 3000 {
+		t.Errorf("parallel FindAny() = %v, %v", got, ok)
+	}
+	if pulled > 2*2*batchSize {
+		t.Errorf("parallel FindAny() pulled %v elements", pulled)
+	}
+	if _, ok := s.FindLast(func(e int) bool { return e > 5 }).Value(); ok {
+		t.Error("FindLast() should not be found")
+	}
+	if !s.NoneMatch(func(e int) bool { return e > 5 }) || s.NoneMatch(even) {
+		t.Error("NoneMatch() is wrong")
+	}
+	empty := FromValues[int]()
+	if !empty.AllMatch(even) || !empty.NoneMatch(even) || empty.AnyMatch(even) {
+		t.Error("match of empty stream is wrong")
+	}
+}
+
+func TestZip(t *testing.T) {
+	names := FromValues("a", "b", "c")
+	got := Zip(names, Iterate(1, func(e int) int { return e + 1 }), func(name string, i int) string {
+		return name + strconv.Itoa(i)
+	}).ToSlice()
+	if !reflect.DeepEqual(got, []string{"a1", "b2", "c3"}) {
+		t.Errorf("Zip() = %v", got)
+	}
+	got = ZipAll(names, FromValues(1), "?", 0, func(name string, i int) string {
+		return name + strconv.Itoa(i)
+	}).ToSlice()
+	if !reflect.DeepEqual(got, []string{"a1", "b0", "c0"}) {
+		t.Errorf("ZipAll() = %v", got)
+	}
+	got = ZipAll(FromValues("a"), FromValues(1, 2, 3), "?", 0, func(name string, i int) string {
+		return name + strconv.Itoa(i)
+	}).ToSlice()
+	if !reflect.DeepEqual(got, []string{"a1", "?2", "?3"}) {
+		t.Errorf("ZipAll() = %v", got)
+	}
+	sum := Of(1, 2, 3).Zip(Of(10, 20), func(e1 T, e2 T) T {
+		return e1.(int) + e2.(int)
+	})
+	if got := sum.ToSlice(); !reflect.DeepEqual(got, []T{11, 22}) {
+		t.Errorf("Zip() = %v", got)
+	}
+	// the stream can be executed again
+	if got := sum.Count(); got != 2 {
+		t.Errorf("Count() = %v, want 2", got)
+	}
+}
+
+func TestInterleave(t *testing.T) {
+	if got := Of(1, 2, 3).Interleave(Of("a")).Join(","); got != "1,a,2,3" {
+		t.Errorf("Interleave() = %v", got)
+	}
+	if got := Of(1).Interleave(Of("a", "b", "c")).Join(","); got != "1,a,b,c" {
+		t.Errorf("Interleave() = %v", got)
+	}
+	if got := Cycle(0).Interleave(Cycle(1)).Limit(5).Join(""); got != "01010" {
+		t.Errorf("Interleave() = %v", got)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	if got := Chunk(FromValues(1, 2, 3, 4, 5), 2).ToSlice(); !reflect.DeepEqual(got, [][]int{{1, 2}, {3, 4}, {5}}) {
+		t.Errorf("Chunk() = %v", got)
+	}
+	if got := Chunk(FromValues(1, 2, 3, 4), 2).ToSlice(); !reflect.DeepEqual(got, [][]int{{1, 2}, {3, 4}}) {
+		t.Errorf("Chunk() = %v", got)
+	}
+	got := Chunk(Iterate(1, func(e int) int { return e + 1 }), 3).Limit(2).ToSlice()
+	if !reflect.DeepEqual(got, [][]int{{1, 2, 3}, {4, 5, 6}}) {
+		t.Errorf("Chunk() of infinite stream = %v", got)
+	}
+	if got := Of(1, 2, 3).Chunk(2).ToSlice(); !reflect.DeepEqual(got, []T{[]T{1, 2}, []T{3}}) {
+		t.Errorf("Chunk() = %v", got)
+	}
+	// chunk then flatten restores the stream
+	if got := Of(1, 2, 3).Limit(3).Chunk(2).Flatten().Join(","); got != "1,2,3" {
+		t.Errorf("Chunk().Flatten() = %v", got)
+	}
+}
+
+func TestWindowed(t *testing.T) {
+	s := FromValues(1, 2, 3, 4, 5)
+	if got := Windowed(s, 3, 1, false).ToSlice(); !reflect.DeepEqual(got, [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}}) {
+		t.Errorf("Windowed() = %v", got)
+	}
+	if got := Windowed(s, 3, 1, true).ToSlice(); !reflect.DeepEqual(got, [][]int{{1, 2, 3}, {2, 3, 4}, {3, 4, 5}, {4, 5}, {5}}) {
+		t.Errorf("Windowed() = %v", got)
+	}
+	if got := Windowed(s, 2, 3, true).ToSlice(); !reflect.DeepEqual(got, [][]int{{1, 2}, {4, 5}}) {
+		t.Errorf("Windowed() = %v", got)
+	}
+	if got := Windowed(s, 1, 2, false).ToSlice(); !reflect.DeepEqual(got, [][]int{{1}, {3}, {5}}) {
+		t.Errorf("Windowed() = %v", got)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := FromValues(1, 2, 3).WithContext(ctx).ToSliceE(); err != context.Canceled || got != nil {
+		t.Errorf("ToSliceE() = %v, %v", got, err)
+	}
+
+	// the infinite stream is stopped by the cancellation
+	ctx, cancel = context.WithCancel(context.Background())
+	consumed := 0
+	err := Generate(func() int { return 1 }).WithContext(ctx).ForEachE(func(e int) error {
+		if consumed++; consumed == 100 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled || consumed != 100 {
+		t.Errorf("ForEachE() = %v after %v elements", err, consumed)
+	}
+
+	// the workers observe the cancellation
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	list := make([]int, 10000)
+	mapped := int32(0)
+	_, err = FromSlice(list).WithContext(ctx).Parallel(4).Map(func(e int) int {
+		if atomic.AddInt32(&mapped, 1) == 100 {
+			cancel()
+		}
+		return e
+	}).ToSliceE()
+	if err != context.Canceled || atomic.LoadInt32(&mapped) == int32(len(list)) {
+		t.Errorf("ToSliceE() = %v after %v elements", err, mapped)
+	}
+
+	// the workers of terminal operations observe the cancellation
+	ctx, cancel = context.WithCancel(context.Background())
+	reduced := int32(0)
+	func() {
+		defer func() {
+			if r := recover(); r != context.Canceled || atomic.LoadInt32(&reduced) >= int32(len(list)) {
+				t.Errorf("Reduce() panics with %v after %v elements", r, reduced)
+			}
+		}()
+		FromSlice(list).WithContext(ctx).Parallel(4).Reduce(func(acc int, e int, i int, n int) int {
+			if atomic.AddInt32(&reduced, 1) == 100 {
+				cancel()
+			}
+			return acc
+		}, 0)
+	}()
+
+	// the operations without error panic with ctx.Err()
+	defer func() {
+		if r := recover(); r != context.Canceled {
+			t.Errorf("Count() panics with %v", r)
+		}
+	}()
+	FromSlice(list).WithContext(ctx).Count()
+}
+
+func TestOfTyped(t *testing.T) {
+	if got := OfInts([]int{1, 2}).Map(func(e int) int { return e * 2 }).ToSlice(); !reflect.DeepEqual(got, []int{2, 4}) {
+		t.Errorf("OfInts() = %v", got)
+	}
+	if got := OfStrings([]string{"a", "b"}).Join("-"); got != "a-b" {
+		t.Errorf("OfStrings() = %v", got)
+	}
+	if got := OfBytes("héllo").Count(); got != 6 {
+		t.Errorf("OfBytes().Count() = %v, want 6", got)
+	}
+	if got := OfRunes("héllo\xff").ToSlice(); !reflect.DeepEqual(got, []rune{'h', 'é', 'l', 'l', 'o', utf8.RuneError}) {
+		t.Errorf("OfRunes() = %v", got)
+	}
+
+	// OfSlice reads the common types without reflection, and the others by reflection
+	tests := []struct {
+		s    T
+		want []T
+	}{
+		{"ab", []T{byte('a'), byte('b')}},
+		{[]byte("ab"), []T{byte('a'), byte('b')}},
+		{[]int{1, 2}, []T{1, 2}},
+		{[]int64{1, 2}, []T{int64(1), int64(2)}},
+		{[]float64{1.5}, []T{1.5}},
+		{[]string{"a"}, []T{"a"}},
+		{[]T{1, "a"}, []T{1, "a"}},
+		{[2]int8{1, 2}, []T{int8(1), int8(2)}},
+	}
+	for _, tt := range tests {
+		if got := OfSlice(tt.s).ToSlice(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("OfSlice(%v) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestConcat(t *testing.T) {
+	tens := FromValues(1, 2, 3).Map(func(e int) int {
+		return e * 10
+	})
+	evens := FromValues(4, 5, 6).Filter(func(e int) bool {
+		return e%2 == 0
+	})
+	// each stream executes its own operations
+	s := tens.Concat(evens)
+	if got := s.ToSlice(); !reflect.DeepEqual(got, []int{10, 20, 30, 4, 6}) {
+		t.Errorf("Concat() = %v", got)
+	}
+	if got := s.Limit(4).Skip(1).ToSlice(); !reflect.DeepEqual(got, []int{20, 30, 4}) {
+		t.Errorf("Concat() = %v", got)
+	}
+	// the streams are not modified
+	if got := tens.Concat(FromValues(0)).ToSlice(); !reflect.DeepEqual(got, []int{10, 20, 30, 0}) {
+		t.Errorf("Concat() = %v", got)
+	}
+	if got := evens.ToSlice(); !reflect.DeepEqual(got, []int{4, 6}) {
+		t.Errorf("ToSlice() = %v", got)
+	}
+}
+
+func TestConcatAll(t *testing.T) {
+	executed := 0
+	lazy := FromValues(1).Map(func(e int) int {
+		executed++
+		return e
+	}).Sort(NaturalOrder[int]())
+	s := ConcatAll(FromValues(1, 2), Iterate(3, func(e int) int { return e + 1 }), lazy)
+	if got := s.Limit(5).ToSlice(); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("ConcatAll() = %v", got)
+	}
+	if executed != 0 {
+		t.Errorf("the stream after the infinite one is executed %v times", executed)
+	}
+	if got := ConcatAll(lazy, FromValues(2).Parallel(2), lazy).Join(","); got != "1,2,1" {
+		t.Errorf("ConcatAll() = %v", got)
+	}
+	if got := ConcatAll[int]().Count(); got != 0 {
+		t.Errorf("ConcatAll() = %v", got)
+	}
+}
+
+func TestReduceWithCombiner(t *testing.T) {
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = strconv.Itoa(i)
+	}
+	length := func(acc int, e string) int { return acc + len(e) }
+	sum := func(a int, b int) int { return a + b }
+	want := ReduceWithCombiner(FromSlice(words), 0, length, sum)
+	if want != 2890 {
+		t.Errorf("ReduceWithCombiner() = %v, want 2890", want)
+	}
+	for _, workers := range []int{1, 2, 3, 8} {
+		if got := ReduceWithCombiner(FromSlice(words).Parallel(workers), 0, length, sum); got != want {
+			t.Errorf("ReduceWithCombiner() with %v workers = %v, want %v", workers, got, want)
+		}
+	}
+
+	// the associative but not commutative operations keep the encounter order
+	join := func(acc string, e string) string { return acc + e }
+	want2 := ReduceWithCombiner(FromSlice(words), "", join, join)
+	if got := ReduceWithCombiner(FromSlice(words).Parallel(4), "", join, join); got != want2 {
+		t.Errorf("parallel ReduceWithCombiner() = %v, want %v", got, want2)
+	}
+	if got := ReduceWithCombiner(FromValues[string]().Parallel(4), 7, length, sum); got != 7 {
+		t.Errorf("ReduceWithCombiner() of empty stream = %v, want the identity", got)
+	}
+}
@@ -0,0 +1,239 @@
+package stream
+
+// FromValues creates a typed Stream from elements
+func FromValues[E any](elements ...E) Stream[E] {
+	return FromSlice(elements)
+}
+
+// FromSlice creates a typed Stream from slice
+func FromSlice[E any](s []E) Stream[E] {
+	return &stream[E]{
+		src: func() iterator {
+			return typedSliceIterator(s)
+		},
+		opts: make([]stage, 0),
+		para: 0,
+	}
+}
+
+// Typed converts an untyped Stream to Stream[E], it panics when the stream runs if an element is not an E
+func Typed[E any](s Stream[T]) Stream[E] {
+	return retype[E](s.(*stream[T]))
+}
+
+// Untyped converts a Stream[E] to the untyped Stream
+func Untyped[E any](s Stream[E]) Stream[T] {
+	return retype[T](s.(*stream[E]))
+}
+
+// ConcatAll concatenates the elements of streams in order. each stream executes its own operations,
+// and it is executed only when the elements of the streams before it run out.
+// the result executes in the mode of the first stream, it is empty if no stream
+func ConcatAll[E any](streams ...Stream[E]) Stream[E] {
+	ret := &stream[E]{opts: make([]stage, 0)}
+	if len(streams) > 0 {
+		first := streams[0].(*stream[E])
+		ret.para, ret.unordered, ret.ctx, ret.hook, ret.unoptimized = first.para, first.unordered, first.ctx, first.hook, first.unoptimized
+	}
+	srcs := make([]func() iterator, len(streams))
+	for i, s := range streams {
+		srcs[i] = s.(*stream[E]).iterator
+	}
+	ret.src = func() iterator {
+		return concatIterator(srcs...)
+	}
+	return ret
+}
+
+// retype returns a stream which shares source and operations with s, but E is the type of elements
+func retype[E, F any](s *stream[F]) *stream[E] {
+	return &stream[E]{
+		src:       s.src,
+		opts:      s.opts,
+		para:      s.para,
+		unordered: s.unordered,
+		ctx:       s.ctx,
+		hook:      s.hook,
+
+		unoptimized: s.unoptimized,
+	}
+}
+
+// Map maps elements of s to another type with function
+func Map[T, R any](s Stream[T], f Function[T, R]) Stream[R] {
+	ret := s.(*stream[T]).addStage("Map",
+		func(ele interface{}, i int) (interface{}, int) {
+			return f(as[T](ele)), actionNext
+		}, stageStateless)
+	return retype[R](ret)
+}
+
+// MapE maps elements of s to another type with function, the stream stops at the first error of f
+func MapE[T, R any](s Stream[T], f FunctionE[T, R]) Stream[R] {
+	ret := s.(*stream[T]).addStage("MapE",
+		func(ele interface{}, i int) (interface{}, int) {
+			r, err := f(as[T](ele))
+			if err != nil {
+				return err, actionFail
+			}
+			return r, actionNext
+		}, stageStateless)
+	return retype[R](ret)
+}
+
+// FlatMap replaces each element of s with the elements of the stream produced by f,
+// the produced stream is executed only when downstream pulls its elements
+func FlatMap[T, R any](s Stream[T], f Function[T, Stream[R]]) Stream[R] {
+	ret := s.(*stream[T]).addStage("FlatMap",
+		func(ele interface{}, i int) (interface{}, int) {
+			return f(as[T](ele)).(*stream[R]).iterator(), actionExpand
+		}, stageStateless)
+	return retype[R](ret)
+}
+
+// FlatMapSlice replaces each element of s with the elements of the slice produced by f
+func FlatMapSlice[T, R any](s Stream[T], f Function[T, []R]) Stream[R] {
+	ret := s.(*stream[T]).addStage("FlatMapSlice",
+		func(ele interface{}, i int) (interface{}, int) {
+			return typedSliceIterator(f(as[T](ele))), actionExpand
+		}, stageStateless)
+	return retype[R](ret)
+}
+
+// Flatten replaces each slice of s with its elements
+func Flatten[T any](s Stream[[]T]) Stream[T] {
+	return FlatMapSlice(s, func(e []T) []T {
+		return e
+	})
+}
+
+// MaxBy return the element of s with the max key, the first one if there are many, empty if no element.
+// the key of each element is computed only once
+func MaxBy[T any, K Ordered](s Stream[T], key Function[T, K]) Optional[T] {
+	return best(s.(*stream[T]), "MaxBy", key, func(k K, than K) bool {
+		return k > than
+	})
+}
+
+// MinBy return the element of s with the min key, the first one if there are many, empty if no element.
+// the key of each element is computed only once
+func MinBy[T any, K Ordered](s Stream[T], key Function[T, K]) Optional[T] {
+	return best(s.(*stream[T]), "MinBy", key, func(k K, than K) bool {
+		return k < than
+	})
+}
+
+// ReduceWithCombiner reduces elements of s to a result of another type. each element is folded into the result by
+// accumulator from identity. when s executes parallel, each chunk is reduced from identity at the same time,
+// and the results of chunks are merged by combiner in encounter order. so the result is the same as sequential if
+//   - identity is the identity of combiner: combiner(identity, r) == r for any r;
+//   - combiner is associative: combiner(combiner(a, b), c) == combiner(a, combiner(b, c));
+//   - accumulator is compatible with combiner: combiner(r, accumulator(identity, e)) == accumulator(r, e).
+//
+// identity is shared by the chunks, so it should not be modified by accumulator if it is a pointer, slice or map
+func ReduceWithCombiner[T, R any](s Stream[T], identity R, accumulator BiFunction[R, T, R], combiner BinaryOperator[R]) (ret R) {
+	reduce := func(prod []interface{}, stopped func() bool) R {
+		acc := identity
+		for _, e := range prod {
+			if stopped() {
+				break
+			}
+			acc = accumulator(acc, as[T](e))
+		}
+		return acc
+	}
+	ret = identity
+	s.(*stream[T]).terminate(stage{
+		name: "ReduceWithCombiner",
+		action: func(ele interface{}, i int) (interface{}, int) {
+			ret = reduce(ele.([]interface{}), never)
+			return nil, actionStop
+		},
+		parallel: func(chunks [][]interface{}, workers int, stopped func() bool) []interface{} {
+			parts := make([]R, len(chunks))
+			runWorkers(workers, len(chunks), func(c int) {
+				parts[c] = reduce(chunks[c], stopped)
+			})
+			for _, part := range parts {
+				ret = combiner(ret, part)
+			}
+			return nil
+		},
+		stageFlag: stageNonShortcut,
+	})
+	return
+}
+
+// SortBy sorts elements of s by the keys extracted by key, the equal elements keep their encounter order.
+// the key of each element is computed only once, which is faster than Sort if key is expensive
+func SortBy[T any, K Ordered](s Stream[T], key Function[T, K]) Stream[T] {
+	type keyed struct {
+		k K
+		e interface{}
+	}
+	withKeys := func(prod []interface{}, stopped func() bool) []interface{} {
+		ret := make([]interface{}, 0, len(prod))
+		for _, e := range prod {
+			if stopped() {
+				break
+			}
+			ret = append(ret, keyed{k: key(as[T](e)), e: e})
+		}
+		return ret
+	}
+	cmp := func(left interface{}, right interface{}) int {
+		return compare(left.(keyed).k, right.(keyed).k)
+	}
+
+	ret := s.(*stream[T]).addStage("SortBy",
+		func(ele interface{}, i int) (interface{}, int) {
+			prod := ele.([]interface{})
+			list := withKeys(prod, never)
+			sortSlice(list, cmp, true)
+			for i, e := range list {
+				prod[i] = e.(keyed).e
+			}
+			return prod, actionNext
+		}, stageStateful)
+	ret.opts[len(ret.opts)-1].parallel = func(chunks [][]interface{}, workers int, stopped func() bool) []interface{} {
+		lists := make([][]interface{}, len(chunks))
+		runWorkers(workers, len(chunks), func(i int) {
+			lists[i] = withKeys(chunks[i], stopped)
+		})
+		list := parallelSort(lists, cmp, workers, true, stopped)
+		for i, e := range list {
+			list[i] = e.(keyed).e
+		}
+		return list
+	}
+	return ret
+}
+
+// Zip combines each element of s with the element of other at the same position by f,
+// the elements of the longer one are ignored
+func Zip[T, U, R any](s Stream[T], other Stream[U], f BiFunction[T, U, R]) Stream[R] {
+	return retype[R](s.(*stream[T]).appendStage(zipStage(other.(*stream[U]).iterator,
+		func(t interface{}, u interface{}) interface{} {
+			return f(as[T](t), as[U](u))
+		}, false, nil, nil)))
+}
+
+// ZipAll combines each element of s with the element of other at the same position by f,
+// the shorter one is padded by padT or padU until both have no more element
+func ZipAll[T, U, R any](s Stream[T], other Stream[U], padT T, padU U, f BiFunction[T, U, R]) Stream[R] {
+	return retype[R](s.(*stream[T]).appendStage(zipStage(other.(*stream[U]).iterator,
+		func(t interface{}, u interface{}) interface{} {
+			return f(as[T](t), as[U](u))
+		}, true, padT, padU)))
+}
+
+// Chunk groups elements of s in slices of size, the last one may be smaller
+func Chunk[T any](s Stream[T], size int) Stream[[]T] {
+	return Windowed(s, size, size, true)
+}
+
+// Windowed produces sliding windows of size, which start every step elements of s.
+// if partial is true, the windows at the end that are smaller than size are produced too
+func Windowed[T any](s Stream[T], size int, step int, partial bool) Stream[[]T] {
+	return retype[[]T](s.(*stream[T]).appendStage(windowStage[T](size, step, partial)))
+}
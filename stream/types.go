@@ -1,98 +1,198 @@
 package stream
 
+import "context"
+
 type (
 	// T is a empty interface, that is `any` type.
-	// since Go is not support generics now(but will coming soon),
-	// so we use T to represent any type
-	T interface{}
+	// it is the element type of the untyped stream, that is Stream[T].
+	// in the generic declarations below T is a type parameter, which shadows this one
+	T = interface{}
 	// R is another `any` type used to distinguish T
-	R interface{}
+	R = interface{}
 	// U is another `any` type used to distinguish T and R
-	U interface{}
+	U = interface{}
 	// Function represents a conversion ability, which accepts one argument and produces a result
-	Function func(e T) R
+	Function[T, R any] func(e T) R
 	// IntFunction is a Function, which result type is int
-	IntFunction func(e T) int
+	IntFunction[T any] func(e T) int
 	// Predicate is a Function, which produces a bool value. usually used to test a value whether satisfied condition
-	Predicate func(e T) bool
+	Predicate[T any] func(e T) bool
 	// UnaryOperator is a Function, which argument and result are the same type
-	UnaryOperator func(e T) T
+	UnaryOperator[T any] func(e T) T
 	// Consumer accepts one argument and not produces any result
-	Consumer func(e T)
+	Consumer[T any] func(e T)
 	// Supplier returns a result. each time invoked it can returns a new or distinct result
-	Supplier func() T
+	Supplier[T any] func() T
 	// BiFunction like Function, but is accepts two arguments and produces a result
-	BiFunction func(t T, u U) R
+	BiFunction[T, U, R any] func(t T, u U) R
 	// BinaryOperator is a BiFunction which input and result are the same type
-	BinaryOperator func(e1 T, e2 T) T
+	BinaryOperator[T any] func(e1 T, e2 T) T
 	// Comparator is a BiFunction, which two input arguments are the type, and returns a int.
 	// if left is greater then right, it returns a positive number;
 	// if left is less then right, it returns a negative number; if the two input are equal, it returns 0
-	Comparator func(left T, right T) int
+	Comparator[T any] func(left T, right T) int
+	// FunctionE is a Function, which may fail with an error
+	FunctionE[T, R any] func(e T) (R, error)
+	// PredicateE is a Predicate, which may fail with an error
+	PredicateE[T any] func(e T) (bool, error)
+	// ConsumerE is a Consumer, which may fail with an error
+	ConsumerE[T any] func(e T) error
 	// Pair is a pair of two element
-	Pair struct {
+	Pair[T, R any] struct {
 		First  T // First is first element
 		Second R // Second is second element
 	}
 )
 
-// Stream is the main interface of stream
-type Stream interface {
+// Number is the constraint of numeric types
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Ordered is the constraint of types which support the operator <
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+// Stream is the main interface of stream, E is the type of elements.
+// Stream[T] is the untyped stream, which elements are any type
+type Stream[E any] interface {
+	// Execution mode
+
+	// Parallel executes the stream with workers goroutines, workers is the number of CPUs if it is not positive
+	Parallel(workers int) Stream[E]
+	// Sequential executes the stream in the caller goroutine
+	Sequential() Stream[E]
+	// Unordered allows parallel execution produce elements out of encounter order
+	Unordered() Stream[E]
+	// WithContext executes the stream with ctx, the execution stops as soon as ctx is done
+	WithContext(ctx context.Context) Stream[E]
+	// Optimize enables or disables the optimizer, which rewrites the operations to the faster ones. it is enabled by default
+	Optimize(enabled bool) Stream[E]
+	// Instrument records the statistics of each operation, and reports them to hook when the stream executes
+	Instrument(hook Hook) Stream[E]
+
+	// Inspection
+
+	// Explain returns the execution plan, which shows the groups of operations and how they are executed
+	Explain() string
+	// ExplainDOT returns the execution plan in Graphviz DOT language
+	ExplainDOT() string
+
 	// Intermediate operations
 	// Stateless operation
 
-	// Concat concat with stream
-	Concat(Stream) Stream
+	// Concat concat with stream, each stream executes its own operations
+	Concat(Stream[E]) Stream[E]
 	// Filter filters out if elements match the condition
-	Filter(Predicate) Stream
+	Filter(Predicate[E]) Stream[E]
+	// FilterE filters out if elements match the condition, the stream stops at the first error of condition
+	FilterE(PredicateE[E]) Stream[E]
+	// FilterField filters out if the fields of path of elements match the condition, like FilterField("Age", Gt(22))
+	FilterField(path string, f Predicate[T]) Stream[E]
+	// FlatMap replaces each element with the elements of the stream produced by function
+	FlatMap(Function[E, Stream[E]]) Stream[E]
+	// FlatMapSlice replaces each element with the elements of the slice produced by function
+	FlatMapSlice(Function[E, []E]) Stream[E]
+	// Flatten replaces each element which is a slice or an array with its elements
+	Flatten() Stream[T]
+	// Interleave takes elements from the stream and other in turn, the rest elements of the longer one are appended
+	Interleave(Stream[E]) Stream[E]
 	// Limit limits elements
-	Limit(int) Stream
-	// Map maps elements with function
-	Map(Function) Stream
+	Limit(int) Stream[E]
+	// Map maps elements with function, use the function Map if the result is another type
+	Map(UnaryOperator[E]) Stream[E]
+	// MapE maps elements with function, the stream stops at the first error of function
+	MapE(FunctionE[E, E]) Stream[E]
 	// Skip skips elements
-	Skip(int) Stream
+	Skip(int) Stream[E]
 	// Slice return the stream with[start, start+count)
-	Slice(start, count int) Stream
+	Slice(start, count int) Stream[E]
+	// Zip combines each element with the element of other at the same position,
+	// the elements of the longer one are ignored
+	Zip(other Stream[E], f BinaryOperator[E]) Stream[E]
+	// ZipAll combines each element with the element of other at the same position,
+	// the shorter one is padded until both have no more element
+	ZipAll(other Stream[E], pad E, f BinaryOperator[E]) Stream[E]
+	// Chunk groups elements in slices of size, the last one may be smaller. the elements of result are []E
+	Chunk(size int) Stream[T]
+	// Windowed produces sliding windows of size, which start every step elements. the elements of result are []E
+	Windowed(size int, step int, partial bool) Stream[T]
 
 	// Stateful operation
 
-	// Fill fill the stream with T
-	Fill(T) Stream
+	// Fill fill the stream with E
+	Fill(E) Stream[E]
 	// Pop pop the last element
-	Pop() Stream
+	Pop() Stream[E]
 	// Push insert the element at last
-	Push(T) Stream
+	Push(E) Stream[E]
 	// Reverse reverse the stream
-	Reverse() Stream
+	Reverse() Stream[E]
 	// Shift remove the first element
-	Shift() Stream
+	Shift() Stream[E]
 	// Unique de-duplicates elements
-	Unique(IntFunction) Stream
+	Unique(IntFunction[E]) Stream[E]
 	// Unshift insert the element at front
-	Unshift(T) Stream
+	Unshift(E) Stream[E]
 	// Sort sorts elements
-	Sort(Comparator) Stream
+	Sort(Comparator[E]) Stream[E]
+	// SortStable sorts elements, the equal elements keep their encounter order
+	SortStable(Comparator[E]) Stream[E]
+	// SortByField sorts elements by the fields of path in ascending order, nil is the greatest
+	SortByField(path string) Stream[E]
 
-	// Terminate operation
+	// Terminate operation.
+	// if a stage fails, the operations return the error if they can, otherwise they panic with the *StageError
 	// Non-short-circuiting
 
 	// Count return the count of stream
 	Count() int
 	// ForEach traversal the stream
-	ForEach(Consumer)
+	ForEach(Consumer[E])
+	// FindLast return the last element that matches the condition, empty if no element matches
+	FindLast(Predicate[E]) Optional[E]
+	// ForEachE traversal the stream, it stops at the first error of function and returns it
+	ForEachE(ConsumerE[E]) error
+	// GroupByField groups elements by the fields of path
+	GroupByField(path string) map[T][]E
 	// Join join all element with splitter
 	Join(string) string
-	// Reduce return nil if no element. calculate result by (R, T) -> R from init element, panic if reduction is nil
-	Reduce(accumulator func(acc R, e T, idx int, sLen int) R, initValue R) R
+	// Last return the last element, empty if no element
+	Last() Optional[E]
+	// Max return the max element by comparator, the first one if there are many, empty if no element
+	Max(Comparator[E]) Optional[E]
+	// Min return the min element by comparator, the first one if there are many, empty if no element
+	Min(Comparator[E]) Optional[E]
+	// Reduce return initValue if no element. calculate result by (E, E) -> E from init element
+	Reduce(accumulator func(acc E, e E, idx int, sLen int) E, initValue E) E
+	// ReduceE like Reduce, but it stops at the first error of accumulator and returns it
+	ReduceE(accumulator func(acc E, e E, idx int, sLen int) (E, error), initValue E) (E, error)
+	// ReduceOptional reduces elements by (E, E) -> E from the first element, empty if no element.
+	// the operator must be associative, since the chunks are reduced at the same time if the stream executes parallel
+	ReduceOptional(BinaryOperator[E]) Optional[E]
 	// ToSlice reduce the stream to slice
-	ToSlice() []T
+	ToSlice() []E
+	// ToSliceE like ToSlice, but it returns the error instead of panic if the execution fails
+	ToSliceE() ([]E, error)
 
 	// Short-circuiting
 
 	// AllMatch test if all elements match the condition
-	AllMatch(Predicate) bool
+	AllMatch(Predicate[E]) bool
 	// AnyMatch test if any element matches the condition
-	AnyMatch(Predicate) bool
-	// FindFirst return the first element that matches the condition
-	FindFirst(Predicate) T
+	AnyMatch(Predicate[E]) bool
+	// FindAny return any element that matches the condition, empty if no element matches.
+	// it is the first one if the stream executes sequential
+	FindAny(Predicate[E]) Optional[E]
+	// FindFirst return the first element that matches the condition, empty if no element matches
+	FindFirst(Predicate[E]) Optional[E]
+	// First return the first element, empty if no element
+	First() Optional[E]
+	// NoneMatch test if no element matches the condition
+	NoneMatch(Predicate[E]) bool
 }